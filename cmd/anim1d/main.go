@@ -34,6 +34,7 @@ func mainImpl() error {
 	intensity := flag.Int("l", int(apa102.DefaultOpts.Intensity), "light intensity [1-255]")
 	temperature := flag.Int("t", int(apa102.DefaultOpts.Temperature), "light temperature in °Kelvin [3500-7500]")
 	fps := flag.Int("fps", 30, "frames per second")
+	gamma := flag.Float64("gamma", 1, "gamma correction to apply to the output; 2.2 to 2.8 is common for LEDs")
 	fileName := flag.String("f", "", "file to load the animation from")
 	raw := flag.String("r", "", "inline serialized animation")
 	flag.Parse()
@@ -56,6 +57,9 @@ func mainImpl() error {
 	if *fps < 1 || *fps > 200 {
 		return errors.New("fps must be between 1 and 200")
 	}
+	if *gamma < 0.1 || *gamma > 10 {
+		return errors.New("gamma must be between 0.1 and 10")
+	}
 	var pat anim1d.SPattern
 	if *fileName != "" {
 		if *raw != "" {
@@ -107,7 +111,11 @@ func mainImpl() error {
 	}
 	// TODO(maruel): Handle Ctrl-C to cleanly exit.
 	defer display.Halt()
-	return runLoop(display, pat.Pattern, *fps)
+	var g *anim1d.Gamma
+	if *gamma != 1 {
+		g = anim1d.MakeGamma(float32(*gamma))
+	}
+	return runLoop(display, pat.Pattern, *fps, g)
 }
 
 type displayWriter interface {
@@ -115,7 +123,7 @@ type displayWriter interface {
 	io.Writer
 }
 
-func runLoop(display displayWriter, p anim1d.Pattern, fps int) error {
+func runLoop(display displayWriter, p anim1d.Pattern, fps int, g *anim1d.Gamma) error {
 	// TODO(maruel): Use double-buffering: one goroutine generates the frames,
 	// the other transmits the data.
 	delta := time.Second / time.Duration(fps)
@@ -127,6 +135,9 @@ func runLoop(display displayWriter, p anim1d.Pattern, fps int) error {
 	for {
 		// Wraps after 49.71 days.
 		p.Render(f, uint32(time.Since(start)/time.Millisecond))
+		if g != nil {
+			g.Apply(f)
+		}
 		f.ToRGB(buf)
 		if _, err := display.Write(buf); err != nil {
 			return err
@@ -10,12 +10,13 @@ package anim1d
 //
 // A good example is using two colors but it can also be animations.
 //
-// TODO(maruel): Support N colors at M positions.
+// Use MultiGradient for more than 2 patterns.
 type Gradient struct {
-	Left  SPattern
-	Right SPattern
-	Curve Curve
-	buf   Frame
+	Left        SPattern
+	Right       SPattern
+	Curve       Curve
+	LinearLight bool // Blend in linear light, which avoids a dark middle between saturated colors
+	buf         Frame
 }
 
 // Render implements Pattern.
@@ -28,13 +29,78 @@ func (g *Gradient) Render(pixels Frame, timeMS uint32) {
 	g.Left.Render(pixels, timeMS)
 	g.Right.Render(g.buf, timeMS)
 	if l == 1 {
-		pixels.Mix(g.buf, g.Curve.Scale8(65535>>1))
+		g.mix(&pixels[0], g.buf[0], g.Curve.Scale8(65535>>1))
 	} else {
 		max := l - 1
 		for i := range pixels {
 			intensity := uint16(i * 65535 / max)
-			pixels[i].Mix(g.buf[i], g.Curve.Scale8(intensity))
+			g.mix(&pixels[i], g.buf[i], g.Curve.Scale8(intensity))
+		}
+	}
+}
+
+func (g *Gradient) mix(c *Color, d Color, gradient uint8) {
+	if g.LinearLight {
+		c.MixLinear(d, gradient)
+	} else {
+		c.Mix(d, gradient)
+	}
+}
+
+// GradientStop is one stop of a MultiGradient.
+type GradientStop struct {
+	Pattern  SPattern // Pattern shown at this stop, usually a Color
+	Position SValue   // Position in pixels; use a Percent to be independent of the strip length
+	Curve    Curve    // Curve of the segment up to the next stop, defaults to EaseOut if not set
+}
+
+// MultiGradient does a gradient between N patterns, each at its own position.
+//
+// Stops must be ordered by Position. Pixels before the first stop and after
+// the last stop are the first and last stop respectively. Two stops at the
+// same position create a hard edge.
+type MultiGradient struct {
+	Stops       []GradientStop
+	LinearLight bool // Blend in linear light, which avoids a dark middle between saturated colors
+	bufs        []Frame
+	pos         []int
+}
+
+// Render implements Pattern.
+func (m *MultiGradient) Render(pixels Frame, timeMS uint32) {
+	l := len(pixels)
+	n := len(m.Stops)
+	if l == 0 || n == 0 {
+		return
+	}
+	if len(m.bufs) != n {
+		m.bufs = make([]Frame, n)
+		m.pos = make([]int, n)
+	}
+	prev := 0
+	for i := range m.Stops {
+		s := &m.Stops[i]
+		m.bufs[i].reset(l)
+		s.Pattern.Render(m.bufs[i], timeMS)
+		// Enforce ordering so a misordered stop is folded onto the previous one.
+		prev = MinMax(int(s.Position.Eval(timeMS, l)), prev, l-1)
+		m.pos[i] = prev
+	}
+	j := 0
+	for i := range pixels {
+		for j < n-1 && i >= m.pos[j+1] {
+			j++
+		}
+		c := m.bufs[j][i]
+		if j < n-1 && i > m.pos[j] {
+			intensity := uint16((i - m.pos[j]) * 65535 / (m.pos[j+1] - m.pos[j]))
+			if m.LinearLight {
+				c.MixLinear(m.bufs[j+1][i], m.Stops[j].Curve.Scale8(intensity))
+			} else {
+				c.Mix(m.bufs[j+1][i], m.Stops[j].Curve.Scale8(intensity))
+			}
 		}
+		pixels[i] = c
 	}
 }
 
@@ -66,7 +132,8 @@ type Transition struct {
 	After        SPattern // New pattern to show
 	OffsetMS     uint32   // Offset at which the transiton from Before->In starts
 	TransitionMS uint32   // Duration of the transition while both are rendered
-	Curve        Curve    // Type of transition, defaults to EaseOut if not set
+	Curve        Curve    // Type of transition, defaults to EaseOut if not set; overshoot is clamped
+	Effect       Effect   // Visual effect of the transition, defaults to Crossfade if not set
 	buf          Frame
 }
 
@@ -77,18 +144,17 @@ func (t *Transition) Render(pixels Frame, timeMS uint32) {
 		t.Before.Render(pixels, timeMS)
 		return
 	}
-	t.After.Render(pixels, timeMS-t.OffsetMS)
 	if timeMS >= t.OffsetMS+t.TransitionMS {
 		// After transition.
+		t.After.Render(pixels, timeMS-t.OffsetMS)
 		t.buf = nil
 		return
 	}
 	t.buf.reset(len(pixels))
-
-	// TODO(maruel): Add lateral animation and others.
-	t.Before.Render(t.buf, timeMS)
+	t.Before.Render(pixels, timeMS)
+	t.After.Render(t.buf, timeMS-t.OffsetMS)
 	intensity := uint16((timeMS - t.OffsetMS) * 65535 / (t.TransitionMS))
-	pixels.Mix(t.buf, 255.-t.Curve.Scale8(intensity))
+	t.Effect.Mix(pixels, t.buf, t.Curve.Scale(intensity))
 }
 
 // Loop rotates between all the animations.
@@ -96,12 +162,12 @@ func (t *Transition) Render(pixels Frame, timeMS uint32) {
 // Display starts with one ShowMS for Patterns[0], then starts looping.
 // timeMS is not modified so it's like as all animations continued animating
 // behind.
-// TODO(maruel): Add lateral transition and others.
 type Loop struct {
 	Patterns     []SPattern
 	ShowMS       uint32 // Duration for each pattern to be shown as pure
 	TransitionMS uint32 // Duration of the transition between two patterns, can be 0
-	Curve        Curve  // Type of transition, defaults to EaseOut if not set
+	Curve        Curve  // Type of transition, defaults to EaseOut if not set; overshoot is clamped
+	Effect       Effect // Visual effect of the transition, defaults to Crossfade if not set
 	buf          Frame
 }
 
@@ -133,7 +199,93 @@ func (l *Loop) Render(pixels Frame, timeMS uint32) {
 	b.Render(l.buf, timeMS)
 	offset -= l.ShowMS
 	intensity := uint16((l.TransitionMS - offset) * 65535 / l.TransitionMS)
-	pixels.Mix(l.buf, l.Curve.Scale8(65535-intensity))
+	l.Effect.Mix(pixels, l.buf, l.Curve.Scale(65535-intensity))
+}
+
+// Cue is one entry of a Sequence.
+type Cue struct {
+	Pattern      SPattern // Receives timeMS relative to StartMS
+	StartMS      uint32   // Time at which the cue starts to be shown
+	DurationMS   uint32   // Duration for which the cue is shown, including the transition
+	TransitionMS uint32   // Duration of the transition into the next cue at the end of this one, can be 0
+	Curve        Curve    // Type of transition, defaults to EaseOut if not set; overshoot is clamped
+	Effect       Effect   // Visual effect of the transition, defaults to Crossfade if not set
+}
+
+// Sequence shows a timeline of cues, each at its own absolute time.
+//
+// When cues overlap, the last one in the list wins. Pixels are black when no
+// cue is active. During a transition, the next cue is rendered at its time
+// relative to its StartMS, or at 0 if it didn't start yet.
+//
+// When Loop is set, the timeline repeats once the last cue ends, and the last
+// cue transitions into the first one.
+type Sequence struct {
+	Cues []Cue
+	Loop bool
+	buf  Frame
+}
+
+// Render implements Pattern.
+func (s *Sequence) Render(pixels Frame, timeMS uint32) {
+	if s.Loop {
+		if end := s.endMS(); end != 0 {
+			timeMS %= end
+		}
+	}
+	i := -1
+	for j := range s.Cues {
+		c := &s.Cues[j]
+		if timeMS >= c.StartMS && timeMS-c.StartMS < c.DurationMS {
+			i = j
+		}
+	}
+	if i == -1 {
+		for j := range pixels {
+			pixels[j] = Color{}
+		}
+		return
+	}
+	c := &s.Cues[i]
+	offset := timeMS - c.StartMS
+	c.Pattern.Render(pixels, offset)
+
+	n := i + 1
+	if n == len(s.Cues) {
+		if !s.Loop {
+			return
+		}
+		n = 0
+	}
+	tr := c.TransitionMS
+	if tr > c.DurationMS {
+		tr = c.DurationMS
+	}
+	if tr == 0 || offset < c.DurationMS-tr {
+		return
+	}
+
+	// Transition.
+	next := &s.Cues[n]
+	nextMS := uint32(0)
+	if n > i && timeMS > next.StartMS {
+		nextMS = timeMS - next.StartMS
+	}
+	s.buf.reset(len(pixels))
+	next.Pattern.Render(s.buf, nextMS)
+	intensity := uint16(uint64(offset-(c.DurationMS-tr)) * 65535 / uint64(tr))
+	c.Effect.Mix(pixels, s.buf, c.Curve.Scale(intensity))
+}
+
+// endMS returns the time at which the last cue ends.
+func (s *Sequence) endMS() uint32 {
+	var end uint32
+	for i := range s.Cues {
+		if e := s.Cues[i].StartMS + s.Cues[i].DurationMS; e > end {
+			end = e
+		}
+	}
+	return end
 }
 
 // Rotate rotates a pattern that can also cycle either way.
@@ -142,11 +294,13 @@ func (l *Loop) Render(pixels Frame, timeMS uint32) {
 //
 // Similar to PingPong{} except that it doesn't bounce.
 //
-// Use 5x oversampling with Scale{} to create smoother animation.
+// Set Interpolation to Linear to create smoother animation with sub-pixel
+// movement.
 type Rotate struct {
-	Child       SPattern
-	MovePerHour MovePerHour // Expressed in number of light jumps per hour.
-	buf         Frame
+	Child         SPattern
+	MovePerHour   MovePerHour   // Expressed in number of light jumps per hour.
+	Interpolation Interpolation // Nearest (default) moves by whole pixels, Linear moves smoothly.
+	buf           Frame
 }
 
 // Render implements Pattern.
@@ -154,6 +308,30 @@ func (r *Rotate) Render(pixels Frame, timeMS uint32) {
 	l := len(pixels)
 	r.buf.reset(l)
 	r.Child.Render(r.buf, timeMS)
+	if r.Interpolation == Linear && l != 0 {
+		pos := r.MovePerHour.Eval256(timeMS, l, l)
+		if pos < 0 {
+			// Reverse direction.
+			pos += l * 256
+		}
+		offset := pos >> 8
+		frac := uint8(pos)
+		for i := range pixels {
+			// Pixel i is between the source pixels offset and offset+1 behind.
+			j := i - offset
+			if j < 0 {
+				j += l
+			}
+			k := j - 1
+			if k < 0 {
+				k += l
+			}
+			c := r.buf[j]
+			c.Mix(r.buf[k], frac)
+			pixels[i] = c
+		}
+		return
+	}
 	offset := r.MovePerHour.Eval(timeMS, len(pixels), l)
 	if offset < 0 {
 		// Reverse direction.
@@ -209,12 +387,14 @@ func (r *Chronometer) Render(pixels Frame, timeMS uint32) {
 // Can be used for a ball, a water wave or K2000 (Knight Rider) style light.
 // The trail can be a Frame or a dynamic pattern.
 //
-// To get smoothed movement, use Scale{} with a 5x factor or so.
-// TODO(maruel): That's a bit inefficient, enable Interpolation here.
+// Set Interpolation to Linear to get smoothed movement with sub-pixel
+// positioning.
 type PingPong struct {
-	Child       SPattern    // [0] is the front pixel so the pixels are effectively drawn in reverse order
-	MovePerHour MovePerHour // Expressed in number of light jumps per hour
-	buf         Frame
+	Child         SPattern      // [0] is the front pixel so the pixels are effectively drawn in reverse order
+	MovePerHour   MovePerHour   // Expressed in number of light jumps per hour
+	Interpolation Interpolation // Nearest (default) moves by whole pixels, Linear moves smoothly.
+	buf           Frame
+	next          Frame
 }
 
 // Render implements Pattern.
@@ -224,6 +404,10 @@ func (p *PingPong) Render(pixels Frame, timeMS uint32) {
 	}
 	p.buf.reset(len(pixels)*2 - 1)
 	p.Child.Render(p.buf, timeMS)
+	if len(pixels) == 1 {
+		pixels[0] = p.buf[0]
+		return
+	}
 	// The last point of each extremity is only lit on one tick but every other
 	// points are lit twice during a full cycle. This means the full cycle is
 	// 2*(len(pixels)-1). For a 3 pixels line, the cycle is: x00, 0x0, 00x, 0x0.
@@ -238,9 +422,28 @@ func (p *PingPong) Render(pixels Frame, timeMS uint32) {
 	//   move == 13 -> "d0123456"
 	//   move 14 -> move 0; "2*(8-1)"
 	cycle := 2 * (len(pixels) - 1)
-	// TODO(maruel): Smoothing with Curve, defaults to Step.
+	if p.Interpolation == Linear {
+		pos := p.MovePerHour.Eval256(timeMS, len(pixels), cycle)
+		if pos < 0 {
+			pos += cycle * 256
+		}
+		p.draw(pixels, pos>>8)
+		if frac := uint8(pos); frac != 0 {
+			p.next.reset(len(pixels))
+			p.draw(p.next, (pos>>8+1)%cycle)
+			pixels.Mix(p.next, frac)
+		}
+		return
+	}
 	pos := p.MovePerHour.Eval(timeMS, len(pixels), cycle)
+	if pos < 0 {
+		pos += cycle
+	}
+	p.draw(pixels, pos)
+}
 
+// draw draws the buffer with the head at pos in the cycle.
+func (p *PingPong) draw(pixels Frame, pos int) {
 	// Once it works the following code looks trivial but everytime it takes me
 	// an absurd amount of time to rewrite it.
 	if pos >= len(pixels)-1 {
@@ -307,6 +510,199 @@ func (s *Subset) Render(pixels Frame, timeMS uint32) {
 	s.Child.Render(pixels[o:o+l], timeMS)
 }
 
+// Reverse renders Child from the end to the beginning.
+//
+// Useful for strips mounted in the opposite direction.
+type Reverse struct {
+	Child SPattern
+}
+
+// Render implements Pattern.
+func (r *Reverse) Render(pixels Frame, timeMS uint32) {
+	r.Child.Render(pixels, timeMS)
+	for i, j := 0, len(pixels)-1; i < j; i, j = i+1, j-1 {
+		pixels[i], pixels[j] = pixels[j], pixels[i]
+	}
+}
+
+// Mirror renders Child once and reflects it around Center.
+//
+// Child is rendered as long as the longest side, starting at Center and going
+// outward in both directions. This is useful for U shaped strips or rings.
+//
+// Center is clamped to [1, len(pixels)] so an animated Center moves
+// continuously; use the Percent "50%" to reflect around the middle.
+type Mirror struct {
+	Child  SPattern
+	Center SValue // Number of pixels before the reflection
+	buf    Frame
+}
+
+// Render implements Pattern.
+func (m *Mirror) Render(pixels Frame, timeMS uint32) {
+	l := len(pixels)
+	if l == 0 {
+		return
+	}
+	c := MinMax(int(m.Center.Eval(timeMS, l)), 1, l)
+	n := c
+	if l-c > n {
+		n = l - c
+	}
+	m.buf.reset(n)
+	m.Child.Render(m.buf, timeMS)
+	for i := 0; i < c; i++ {
+		pixels[c-1-i] = m.buf[i]
+	}
+	copy(pixels[c:], m.buf)
+}
+
+// Kaleidoscope renders Child once and tiles it, reflecting every other tile.
+type Kaleidoscope struct {
+	Child SPattern
+	Tiles SValue // Number of tiles; 0 (default) is 2.
+	buf   Frame
+}
+
+// Render implements Pattern.
+func (k *Kaleidoscope) Render(pixels Frame, timeMS uint32) {
+	l := len(pixels)
+	if l == 0 {
+		return
+	}
+	n := MinMax(int(k.Tiles.Eval(timeMS, l)), 0, l)
+	if n == 0 {
+		n = 2
+	}
+	w := (l + n - 1) / n
+	k.buf.reset(w)
+	k.Child.Render(k.buf, timeMS)
+	for i := range pixels {
+		j := i % w
+		if (i/w)&1 != 0 {
+			j = w - 1 - j
+		}
+		pixels[i] = k.buf[j]
+	}
+}
+
+// TimeShift renders Child with its time offset by OffsetMS.
+//
+// A positive offset makes Child ahead in time, a negative one delays it. Child
+// stays at its time 0 until the delay is elapsed.
+type TimeShift struct {
+	Child    SPattern
+	OffsetMS SValue
+}
+
+// Render implements Pattern.
+func (t *TimeShift) Render(pixels Frame, timeMS uint32) {
+	t.Child.Render(pixels, clampTime(int64(timeMS)+int64(t.OffsetMS.Eval(timeMS, len(pixels)))))
+}
+
+// TimeScale renders Child with its time scaled by Numerator/Denominator,
+// starting at StartMS.
+//
+// For example 1/2 plays at half speed. A negative ratio plays backward from
+// StartMS and Child stays at its time 0 once it is reached.
+type TimeScale struct {
+	Child       SPattern
+	Numerator   int32
+	Denominator int32 // 0 is treated as 1.
+	StartMS     uint32
+}
+
+// Render implements Pattern.
+func (t *TimeScale) Render(pixels Frame, timeMS uint32) {
+	d := int64(t.Denominator)
+	if d == 0 {
+		d = 1
+	}
+	t.Child.Render(pixels, clampTime(int64(t.StartMS)+int64(timeMS)*int64(t.Numerator)/d))
+}
+
+// clampTime converts a calculated time to the valid range.
+func clampTime(t int64) uint32 {
+	if t < 0 {
+		return 0
+	}
+	if t > 0xFFFFFFFF {
+		return 0xFFFFFFFF
+	}
+	return uint32(t)
+}
+
+// Freeze renders Child at a fixed instant.
+type Freeze struct {
+	Child SPattern
+	AtMS  uint32
+}
+
+// Render implements Pattern.
+func (f *Freeze) Render(pixels Frame, timeMS uint32) {
+	f.Child.Render(pixels, f.AtMS)
+}
+
+// TimeLoop renders Child repeatedly over the time range [StartMS,
+// StartMS+DurationMS).
+type TimeLoop struct {
+	Child      SPattern
+	StartMS    uint32
+	DurationMS uint32 // 0 freezes Child at StartMS.
+}
+
+// Render implements Pattern.
+func (t *TimeLoop) Render(pixels Frame, timeMS uint32) {
+	if t.DurationMS == 0 {
+		t.Child.Render(pixels, t.StartMS)
+		return
+	}
+	t.Child.Render(pixels, t.StartMS+timeMS%t.DurationMS)
+}
+
+// Delay renders Child with a time delay that increases along the strip, so
+// pixel i sees timeMS - i*DelayMS. Child stays at its time 0 on the pixels
+// whose delay isn't elapsed yet.
+//
+// This turns any temporal animation, like a Loop of colors, into a wave
+// traveling along the strip. A negative DelayMS makes the wave travel the other
+// way.
+//
+// Child is rendered once per distinct delay, which can be costly on long
+// strips. Set MaxRenders to group neighbor pixels together so Child is
+// rendered at most MaxRenders times per frame.
+type Delay struct {
+	Child      SPattern
+	DelayMS    SValue // Delay added for each pixel
+	MaxRenders int    // Maximum number of times Child is rendered per frame; 0 means no limit
+	buf        Frame
+}
+
+// Render implements Pattern.
+func (d *Delay) Render(pixels Frame, timeMS uint32) {
+	l := len(pixels)
+	delay := d.DelayMS.Eval(timeMS, l)
+	if delay == 0 || l == 0 {
+		d.Child.Render(pixels, timeMS)
+		return
+	}
+	// Number of pixels sharing the same render.
+	w := 1
+	if d.MaxRenders > 0 && l > d.MaxRenders {
+		w = (l + d.MaxRenders - 1) / d.MaxRenders
+	}
+	d.buf.reset(l)
+	for i := 0; i < l; i += w {
+		end := i + w
+		if end > l {
+			end = l
+		}
+		// Use the delay of the middle of the group.
+		d.Child.Render(d.buf, clampTime(int64(timeMS)-int64(i+end-1)*int64(delay)/2))
+		copy(pixels[i:end], d.buf[i:end])
+	}
+}
+
 // Dim is a filter that dim the intensity of a buffer.
 type Dim struct {
 	Child     SPattern //
@@ -320,6 +716,83 @@ func (d *Dim) Render(pixels Frame, timeMS uint32) {
 	pixels.Dim(uint8(i))
 }
 
+// Mask is a filter that scales the intensity of each pixel of Child by the
+// level of the corresponding pixel of Mask.
+//
+// Use a Rotate or a PingPong as the Mask to create moving spotlights over any
+// animation.
+type Mask struct {
+	Child   SPattern
+	Mask    SPattern
+	Channel Channel // Channel of Mask to use; defaults to luminance.
+	buf     Frame
+}
+
+// Render implements Pattern.
+func (m *Mask) Render(pixels Frame, timeMS uint32) {
+	m.Child.Render(pixels, timeMS)
+	m.buf.reset(len(pixels))
+	m.Mask.Render(m.buf, timeMS)
+	for i := range pixels {
+		l := m.Channel.Eval(m.buf[i])
+		c := &pixels[i]
+		c.R, c.G, c.B = mul255(c.R, l), mul255(c.G, l), mul255(c.B, l)
+	}
+}
+
+// MapPalette is a filter that replaces each pixel of Child by the color of
+// Palette at its level.
+//
+// Value is added to the level, so it rotates the colors when Palette.Wrap is
+// set. When Child is not set, Value alone selects the color for all pixels.
+type MapPalette struct {
+	Child   SPattern
+	Channel Channel // Channel of Child to use; defaults to luminance
+	Value   SValue  // Added to the level, in [0, 255]
+	Palette Palette
+}
+
+// Render implements Pattern.
+func (m *MapPalette) Render(pixels Frame, timeMS uint32) {
+	if m.Child.Pattern == nil {
+		for i := range pixels {
+			pixels[i] = Color{}
+		}
+	} else {
+		m.Child.Render(pixels, timeMS)
+	}
+	v := m.Value.Eval(timeMS, len(pixels))
+	for i := range pixels {
+		level := int32(m.Channel.Eval(pixels[i])) + v
+		if m.Palette.Wrap {
+			level &= 0xff
+		} else {
+			level = MinMax32(level, 0, 255)
+		}
+		pixels[i] = m.Palette.At8(uint8(level))
+	}
+}
+
+// HueShift is a filter that rotates the hue of each pixel.
+type HueShift struct {
+	Child SPattern
+	Shift SValue // In degrees; use an Oscillator or an Equation to make it cycle
+}
+
+// Render implements Pattern.
+func (h *HueShift) Render(pixels Frame, timeMS uint32) {
+	h.Child.Render(pixels, timeMS)
+	shift := uint16(int64(h.Shift.Eval(timeMS, len(pixels))) * 65536 / 360)
+	if shift == 0 {
+		return
+	}
+	for i := range pixels {
+		c := pixels[i].HSV()
+		c.H += shift
+		pixels[i] = c.ToRGB()
+	}
+}
+
 // Add is a generic mixer that merges the output from multiple patterns with
 // saturation.
 type Add struct {
@@ -340,10 +813,196 @@ func (a *Add) Render(pixels Frame, timeMS uint32) {
 	}
 }
 
+// Layer is one layer of Layers.
+type Layer struct {
+	Pattern SPattern
+	Blend   Blend
+	// Transparency is 0 (default) for fully opaque up to 255 for invisible.
+	Transparency SValue
+	// Alpha is optional. When set, its luminance is the coverage of each pixel
+	// of Pattern, which is then drawn as is. See Blend.MixAlpha.
+	Alpha SPattern
+}
+
+// Layers stacks patterns on top of each other, from the bottom to the top,
+// each combined with the ones below with its own blend mode and transparency.
+//
+// Unlike Add, a sparse pattern on a background doesn't brighten it with
+// BlendNormal. Without Alpha, the coverage of a pixel is its brightest channel
+// so a dark color is mostly transparent; set Alpha to draw dark colors.
+type Layers struct {
+	Layers []Layer
+	buf    Frame
+	alpha  Frame
+}
+
+// Render implements Pattern.
+func (l *Layers) Render(pixels Frame, timeMS uint32) {
+	for i := range pixels {
+		pixels[i] = Color{}
+	}
+	for i := range l.Layers {
+		y := &l.Layers[i]
+		opacity := 255 - MinMax32(y.Transparency.Eval(timeMS, len(pixels)), 0, 255)
+		if opacity == 0 {
+			continue
+		}
+		// Patterns may not write all the pixels.
+		l.buf.reset(len(pixels))
+		y.Pattern.Render(l.buf, timeMS)
+		if y.Alpha.Pattern == nil {
+			y.Blend.Mix(pixels, l.buf, uint8(opacity))
+			continue
+		}
+		l.alpha.reset(len(pixels))
+		y.Alpha.Render(l.alpha, timeMS)
+		y.Blend.MixAlpha(pixels, l.buf, l.alpha, uint8(opacity))
+	}
+}
+
+// Meter fills the strip proportionally to Value, like a progress bar or a
+// volume indicator.
+//
+// The last pixel of the filled part is anti-aliased. The peak marker, when
+// enabled, is held then decays; it is calculated by sampling the past values
+// of Value so it stays stateless.
+type Meter struct {
+	Value       SValue
+	Min         int32    // Value at which the meter is empty
+	Max         int32    // Value at which the meter is full; the range defaults to [0, 255] when Min and Max are equal
+	Center      bool     // Fills from the center outward instead of from the beginning
+	Reverse     bool     // Fills from the end instead of the beginning
+	Fill        SPattern // Filled part; defaults to white
+	Empty       SPattern // Empty part; defaults to black
+	Peak        SPattern // Peak marker; defaults to white
+	PeakHoldMS  uint32   // Duration for which the peak is held; 0 disables the peak marker
+	PeakDecayMS uint32   // Duration for the peak to fall over the whole range once the hold is over
+	fill        Frame
+	peak        Frame
+}
+
+// meterSamples is the maximum number of past values sampled to calculate the
+// peak.
+const meterSamples = 64
+
+// Render implements Pattern.
+func (m *Meter) Render(pixels Frame, timeMS uint32) {
+	l := len(pixels)
+	if l == 0 {
+		return
+	}
+	if m.Empty.Pattern == nil {
+		for i := range pixels {
+			pixels[i] = Color{}
+		}
+	} else {
+		m.Empty.Render(pixels, timeMS)
+	}
+	white := Color{0xFF, 0xFF, 0xFF}
+	m.fill.reset(l)
+	if m.Fill.Pattern == nil {
+		white.Render(m.fill, timeMS)
+	} else {
+		m.Fill.Render(m.fill, timeMS)
+	}
+	// All the lengths are in 1/256th of pixel.
+	level := m.level(m.Value.Eval(timeMS, l), l)
+	start, end := m.span(level, l)
+	for i := range pixels {
+		// Coverage of the pixel by the filled part.
+		cover := MinMax(end-i*256, 0, 256) - MinMax(start-i*256, 0, 256)
+		j := i
+		if m.Reverse {
+			j = l - 1 - i
+		}
+		mixCover(&pixels[j], m.fill[j], cover)
+	}
+
+	if m.PeakHoldMS == 0 {
+		return
+	}
+	peak := m.peakLevel(timeMS, l)
+	if peak <= 0 {
+		return
+	}
+	m.peak.reset(l)
+	if m.Peak.Pattern == nil {
+		white.Render(m.peak, timeMS)
+	} else {
+		m.Peak.Render(m.peak, timeMS)
+	}
+	start, end = m.span(peak, l)
+	ends := [2]int{(end - 1) / 256, start / 256}
+	n := 1
+	if m.Center {
+		// The fill grows in both directions.
+		n = 2
+	}
+	for _, i := range ends[:n] {
+		i = MinMax(i, 0, l-1)
+		if m.Reverse {
+			i = l - 1 - i
+		}
+		pixels[i] = m.peak[i]
+	}
+}
+
+// level returns the length filled for the value v.
+func (m *Meter) level(v int32, l int) int {
+	lo, hi := int64(m.Min), int64(m.Max)
+	if lo == hi {
+		lo, hi = 0, 255
+	}
+	x := (int64(v) - lo) * int64(l) * 256 / (hi - lo)
+	if x < 0 {
+		return 0
+	}
+	if x > int64(l)*256 {
+		return l * 256
+	}
+	return int(x)
+}
+
+// span returns the part of the strip filled for the length level.
+func (m *Meter) span(level, l int) (int, int) {
+	if m.Center {
+		return l*128 - level/2, l*128 + (level+1)/2
+	}
+	return 0, level
+}
+
+// peakLevel returns the length of the peak, held then decaying.
+//
+// It samples the past values at times aligned on a step so the result is
+// stable from one frame to the next.
+func (m *Meter) peakLevel(timeMS uint32, l int) int {
+	window := m.PeakHoldMS + m.PeakDecayMS
+	step := window / meterSamples
+	if step < 10 {
+		step = 10
+	}
+	peak := m.level(m.Value.Eval(timeMS, l), l)
+	t := timeMS - timeMS%step
+	for age := timeMS - t; age <= window && age <= timeMS; age += step {
+		v := m.level(m.Value.Eval(timeMS-age, l), l)
+		if age > m.PeakHoldMS {
+			if m.PeakDecayMS == 0 {
+				break
+			}
+			v -= int(int64(age-m.PeakHoldMS) * int64(l) * 256 / int64(m.PeakDecayMS))
+		}
+		if v > peak {
+			peak = v
+		}
+	}
+	return peak
+}
+
 // Scale adapts a larger or smaller patterns to the Strip size
 //
-// This is useful to create smoother horizontal movement animation or to scale
-// up/down images.
+// This is useful to scale up/down images. For smooth horizontal movement, use
+// Rotate or PingPong with Interpolation set to Linear instead of rendering a
+// larger buffer.
 type Scale struct {
 	Child SPattern
 	// Defaults to Linear
@@ -359,16 +1018,20 @@ type Scale struct {
 
 // Render implements Pattern.
 func (s *Scale) Render(pixels Frame, timeMS uint32) {
+	i := s.Interpolation
+	if i == "" {
+		i = Linear
+	}
 	if f, ok := s.Child.Pattern.(Frame); ok {
 		if s.RatioMilli.Eval(timeMS, len(pixels)) == 0 {
-			s.Interpolation.Scale(f, pixels)
+			i.Scale(f, pixels)
 			return
 		}
 	}
 	v := MinMax32(s.RatioMilli.Eval(timeMS, len(pixels)), 1, 1000000)
 	s.buf.reset((int(v)*len(pixels) + 500) / 1000)
 	s.Child.Render(s.buf, timeMS)
-	s.Interpolation.Scale(s.buf, pixels)
+	i.Scale(s.buf, pixels)
 }
 
 // Repeated repeats a Frame to fill the pixels.
@@ -14,12 +14,107 @@ func TestGradient(t *testing.T) {
 	testFrame(t, &Gradient{Left: SPattern{a}, Right: SPattern{b}, Curve: Direct}, expectation{0, Frame{{0x10, 0x10, 0x10}, {0x18, 0x18, 0x18}, {0x20, 0x20, 0x20}}})
 }
 
+func TestGradientLinearLight(t *testing.T) {
+	a := &Color{0xFF, 0x00, 0x00}
+	b := &Color{0x00, 0xFF, 0x00}
+	p := &Gradient{Left: SPattern{a}, Right: SPattern{b}, Curve: Direct, LinearLight: true}
+	testFrame(t, p, expectation{0, Frame{*a, {0xBC, 0xBB, 0x00}, *b}})
+}
+
+func TestMultiGradient(t *testing.T) {
+	a := &Color{0x00, 0x00, 0x00}
+	b := &Color{0x20, 0x20, 0x20}
+	c := &Color{0x40, 0x40, 0x40}
+	p := &MultiGradient{
+		Stops: []GradientStop{
+			{Pattern: SPattern{a}, Position: SValue{Const(0)}, Curve: Direct},
+			{Pattern: SPattern{b}, Position: SValue{Percent(32768)}, Curve: Direct},
+			{Pattern: SPattern{c}, Position: SValue{Percent(65536)}},
+		},
+	}
+	testFrame(t, p, expectation{0, Frame{*a, {0x10, 0x10, 0x10}, *b, {0x30, 0x30, 0x30}, *c}})
+	// Hard edge and clamping outside the stops.
+	p = &MultiGradient{
+		Stops: []GradientStop{
+			{Pattern: SPattern{a}, Position: SValue{Const(1)}},
+			{Pattern: SPattern{c}, Position: SValue{Const(1)}},
+		},
+	}
+	testFrame(t, p, expectation{0, Frame{*a, *c, *c}})
+}
+
 func TestTransition(t *testing.T) {
-	// TODO(maruel): Add.
+	a := Color{0x10, 0x10, 0x10}
+	b := Color{0x20, 0x20, 0x20}
+	p := &Transition{
+		Before:       SPattern{&a},
+		After:        SPattern{&b},
+		OffsetMS:     100,
+		TransitionMS: 200,
+		Curve:        Direct,
+		Effect:       WipeRight,
+	}
+	e := []expectation{
+		{0, Frame{a, a, a, a}},
+		{100, Frame{a, a, a, a}},
+		{200, Frame{b, b, a, a}},
+		{300, Frame{b, b, b, b}},
+		{1000, Frame{b, b, b, b}},
+	}
+	testFrames(t, p, e)
 }
 
 func TestLoop(t *testing.T) {
-	// TODO(maruel): Add.
+	a := Color{0x10, 0x10, 0x10}
+	b := Color{0x20, 0x20, 0x20}
+	p := &Loop{
+		Patterns:     []SPattern{{&a}, {&b}},
+		ShowMS:       100,
+		TransitionMS: 100,
+		Curve:        Direct,
+		Effect:       PushLeft,
+	}
+	e := []expectation{
+		{0, Frame{a, a, a, a}},
+		{100, Frame{a, a, a, a}},
+		{150, Frame{a, a, b, b}},
+		{200, Frame{b, b, b, b}},
+		{350, Frame{b, b, a, a}},
+		{400, Frame{a, a, a, a}},
+	}
+	testFrames(t, p, e)
+}
+
+func TestSequence(t *testing.T) {
+	b := Color{0x20, 0x20, 0x20}
+	c := Color{0x30, 0x30, 0x30}
+	p := &Sequence{
+		Cues: []Cue{
+			{Pattern: SPattern{timePattern{}}, DurationMS: 100, TransitionMS: 50, Curve: Direct, Effect: PushLeft},
+			{Pattern: SPattern{&b}, StartMS: 100, DurationMS: 100},
+			{Pattern: SPattern{&c}, StartMS: 300, DurationMS: 50},
+		},
+	}
+	t10 := Color{0, 10, 0}
+	t75 := Color{0, 75, 0}
+	e := []expectation{
+		{10, Frame{t10, t10, t10, t10}},
+		{75, Frame{t75, t75, b, b}},
+		{150, Frame{b, b, b, b}},
+		{250, Frame{{}, {}, {}, {}}},
+		{320, Frame{c, c, c, c}},
+		{360, Frame{{}, {}, {}, {}}},
+	}
+	testFrames(t, p, e)
+	p.Loop = true
+	p.Cues[2].TransitionMS = 50
+	p.Cues[2].Curve = Direct
+	p.Cues[2].Effect = PushLeft
+	e = []expectation{
+		{340, Frame{c, {}, {}, {}}},
+		{360, Frame{t10, t10, t10, t10}},
+	}
+	testFrames(t, p, e)
 }
 
 func TestRotate(t *testing.T) {
@@ -59,6 +154,26 @@ func TestRotateRev(t *testing.T) {
 	testFrames(t, p, e)
 }
 
+func TestRotateLinear(t *testing.T) {
+	a := Color{0x10, 0x10, 0x10}
+	b := Color{0x20, 0x20, 0x20}
+	c := Color{0x30, 0x30, 0x30}
+	p := &Rotate{Child: SPattern{Frame{a, b, c}}, MovePerHour: MovePerHour{Const(360000)}, Interpolation: Linear}
+	e := []expectation{
+		{0, Frame{a, b, c}},
+		{5, Frame{{0x20, 0x20, 0x20}, {0x18, 0x18, 0x18}, {0x28, 0x28, 0x28}}},
+		{10, Frame{c, a, b}},
+	}
+	testFrames(t, p, e)
+	p.MovePerHour = MovePerHour{Const(-360000)}
+	e = []expectation{
+		{0, Frame{a, b, c}},
+		{5, Frame{{0x18, 0x18, 0x18}, {0x28, 0x28, 0x28}, {0x20, 0x20, 0x20}}},
+		{10, Frame{b, c, a}},
+	}
+	testFrames(t, p, e)
+}
+
 func TestChronometer(t *testing.T) {
 	r := Color{0xff, 0x00, 0x00}
 	g := Color{0x00, 0xff, 0x00}
@@ -111,6 +226,21 @@ func TestPingPong(t *testing.T) {
 	testFrames(t, p, exp)
 }
 
+func TestPingPongLinear(t *testing.T) {
+	a := Color{0x10, 0x10, 0x10}
+	b := Color{0x20, 0x20, 0x20}
+	p := &PingPong{Child: SPattern{Frame{a, b}}, MovePerHour: MovePerHour{Const(360000)}, Interpolation: Linear}
+	exp := []expectation{
+		{0, Frame{a, b, {}}},
+		{5, Frame{{0x18, 0x18, 0x18}, {0x18, 0x18, 0x18}, {}}},
+		{10, Frame{b, a, {}}},
+		{35, Frame{{0x08, 0x08, 0x08}, {0x18, 0x18, 0x18}, {0x10, 0x10, 0x10}}},
+		{40, Frame{a, b, {}}},
+	}
+	testFrames(t, p, exp)
+	testFrame(t, p, expectation{5, Frame{a}})
+}
+
 func TestCrop(t *testing.T) {
 	// Crop skips the beginning and the end of the source.
 	f := Frame{
@@ -145,6 +275,132 @@ func TestDim(t *testing.T) {
 	testFrame(t, p, expectation{0, Frame{{0x2f, 0x2f, 0x2f}}})
 }
 
+func TestReverse(t *testing.T) {
+	a, b, c := Color{1, 1, 1}, Color{2, 2, 2}, Color{3, 3, 3}
+	p := &Reverse{Child: SPattern{Frame{a, b, c}}}
+	testFrame(t, p, expectation{0, Frame{c, b, a}})
+	testFrame(t, p, expectation{0, Frame{b, a}})
+}
+
+func TestMirror(t *testing.T) {
+	a, b, c := Color{1, 1, 1}, Color{2, 2, 2}, Color{3, 3, 3}
+	p := &Mirror{Child: SPattern{Frame{a, b, c}}, Center: SValue{Percent(32768)}}
+	testFrame(t, p, expectation{0, Frame{c, b, a, a, b, c}})
+	testFrame(t, p, expectation{0, Frame{b, a, a, b, c}})
+	p.Center = SValue{Const(1)}
+	testFrame(t, p, expectation{0, Frame{a, a, b, c}})
+	// Clamped to 1 so an animated Center doesn't jump.
+	p.Center = SValue{Const(0)}
+	testFrame(t, p, expectation{0, Frame{a, a, b}})
+	p.Center = SValue{Percent(65536)}
+	testFrame(t, p, expectation{0, Frame{c, b, a}})
+}
+
+func TestKaleidoscope(t *testing.T) {
+	a, b, c := Color{1, 1, 1}, Color{2, 2, 2}, Color{3, 3, 3}
+	p := &Kaleidoscope{Child: SPattern{Frame{a, b, c}}}
+	testFrame(t, p, expectation{0, Frame{a, b, c, c, b, a}})
+	p.Tiles = SValue{Const(3)}
+	testFrame(t, p, expectation{0, Frame{a, b, b, a, a, b}})
+	testFrame(t, p, expectation{0, Frame{a, b, b, a, a}})
+	p.Tiles = SValue{Const(1)}
+	testFrame(t, p, expectation{0, Frame{a, b, c}})
+	p.Tiles = SValue{Const(0)}
+	testFrame(t, p, expectation{0, Frame{a, b, b}})
+}
+
+// timePattern renders timeMS in the red and green channels.
+type timePattern struct{}
+
+func (timePattern) Render(pixels Frame, timeMS uint32) {
+	for i := range pixels {
+		pixels[i] = Color{uint8(timeMS >> 8), uint8(timeMS), 0}
+	}
+}
+
+func TestTimeShift(t *testing.T) {
+	p := &TimeShift{Child: SPattern{timePattern{}}, OffsetMS: SValue{Const(-10)}}
+	testFrame(t, p, expectation{100, Frame{{0, 90, 0}}})
+	p.OffsetMS = SValue{Const(1000)}
+	testFrame(t, p, expectation{100, Frame{{4, 76, 0}}})
+	p.OffsetMS = SValue{Const(-1000)}
+	testFrame(t, p, expectation{100, Frame{{0, 0, 0}}})
+}
+
+func TestTimeScale(t *testing.T) {
+	p := &TimeScale{Child: SPattern{timePattern{}}, Numerator: 1, Denominator: 2}
+	testFrame(t, p, expectation{100, Frame{{0, 50, 0}}})
+	p.Numerator, p.StartMS = -3, 1000
+	testFrame(t, p, expectation{100, Frame{{3, 0x52, 0}}})
+	testFrame(t, p, expectation{700, Frame{{0, 0, 0}}})
+	p.StartMS = 0
+	p.Numerator, p.Denominator = 2, 0
+	testFrame(t, p, expectation{100, Frame{{0, 200, 0}}})
+}
+
+func TestFreeze(t *testing.T) {
+	p := &Freeze{Child: SPattern{timePattern{}}, AtMS: 42}
+	testFrame(t, p, expectation{100, Frame{{0, 42, 0}}})
+}
+
+func TestTimeLoop(t *testing.T) {
+	p := &TimeLoop{Child: SPattern{timePattern{}}, StartMS: 10, DurationMS: 20}
+	e := []expectation{
+		{0, Frame{{0, 10, 0}}},
+		{19, Frame{{0, 29, 0}}},
+		{20, Frame{{0, 10, 0}}},
+		{45, Frame{{0, 15, 0}}},
+	}
+	testFrames(t, p, e)
+	p.DurationMS = 0
+	testFrame(t, p, expectation{45, Frame{{0, 10, 0}}})
+}
+
+func TestDelay(t *testing.T) {
+	p := &Delay{Child: SPattern{timePattern{}}, DelayMS: SValue{Const(10)}}
+	testFrame(t, p, expectation{100, Frame{{0, 100, 0}, {0, 90, 0}, {0, 80, 0}, {0, 70, 0}, {0, 60, 0}}})
+	p.MaxRenders = 2
+	testFrame(t, p, expectation{100, Frame{{0, 90, 0}, {0, 90, 0}, {0, 90, 0}, {0, 65, 0}, {0, 65, 0}}})
+	p.MaxRenders = 0
+	p.DelayMS = SValue{Const(50)}
+	testFrame(t, p, expectation{100, Frame{{0, 100, 0}, {0, 50, 0}, {0, 0, 0}, {0, 0, 0}}})
+	p.DelayMS = SValue{Const(-10)}
+	testFrame(t, p, expectation{100, Frame{{0, 100, 0}, {0, 110, 0}, {0, 120, 0}}})
+	p.DelayMS = SValue{}
+	testFrame(t, p, expectation{100, Frame{{0, 100, 0}, {0, 100, 0}}})
+}
+
+func TestMask(t *testing.T) {
+	child := Frame{{0xFF, 0x80, 0x40}, {0xFF, 0x80, 0x40}, {0xFF, 0x80, 0x40}, {0xFF, 0x80, 0x40}}
+	mask := Frame{{}, {0xFF, 0xFF, 0xFF}, {0x00, 0x00, 0xFF}, {0xFF, 0x00, 0x00}}
+	p := &Mask{Child: SPattern{child}, Mask: SPattern{mask}}
+	testFrame(t, p, expectation{0, Frame{{}, {0xFF, 0x80, 0x40}, {0x13, 0x0A, 0x05}, {0x36, 0x1B, 0x0E}}})
+	p.Channel = ChannelBlue
+	testFrame(t, p, expectation{0, Frame{{}, {0xFF, 0x80, 0x40}, {0xFF, 0x80, 0x40}, {}}})
+	p.Channel = ChannelMax
+	testFrame(t, p, expectation{0, Frame{{}, {0xFF, 0x80, 0x40}, {0xFF, 0x80, 0x40}, {0xFF, 0x80, 0x40}}})
+}
+
+func TestMapPalette(t *testing.T) {
+	p := &MapPalette{
+		Child:   SPattern{Frame{{}, {0xFF, 0xFF, 0xFF}, {0x80, 0x80, 0x80}}},
+		Palette: Palette{Stops: []PaletteStop{{Color{}, 0}, {Color{0xFF, 0, 0}, 65535}}},
+	}
+	testFrame(t, p, expectation{0, Frame{{}, {0xFF, 0, 0}, {0x80, 0, 0}}})
+	p.Value = SValue{Const(10)}
+	testFrame(t, p, expectation{0, Frame{{0x0A, 0, 0}, {0xFF, 0, 0}, {0x8A, 0, 0}}})
+	p.Child = SPattern{}
+	p.Value = SValue{Const(255)}
+	testFrame(t, p, expectation{0, Frame{{0xFF, 0, 0}, {0xFF, 0, 0}}})
+}
+
+func TestHueShift(t *testing.T) {
+	p := &HueShift{Child: SPattern{Frame{{0xFF, 0, 0}, {0, 0xFF, 0}, {0x40, 0x40, 0x40}}}, Shift: SValue{Const(120)}}
+	testFrame(t, p, expectation{0, Frame{{0, 0xFF, 0}, {0, 0, 0xFF}, {0x40, 0x40, 0x40}}})
+	p.Shift = SValue{Const(-120)}
+	testFrame(t, p, expectation{0, Frame{{0, 0, 0xFF}, {0xFF, 0, 0}, {0x40, 0x40, 0x40}}})
+}
+
 func TestAdd(t *testing.T) {
 	a := Color{0x60, 0x60, 0x60}
 	b := Color{0x10, 0x20, 0x30}
@@ -152,9 +408,72 @@ func TestAdd(t *testing.T) {
 	testFrame(t, p, expectation{0, Frame{{0x70, 0x80, 0x90}}})
 }
 
+func TestLayers(t *testing.T) {
+	bg := Color{0x40, 0x40, 0x40}
+	sparkle := Frame{{}, {0xFF, 0xFF, 0xFF}, {}}
+	p := &Layers{
+		Layers: []Layer{
+			{Pattern: SPattern{&bg}},
+			{Pattern: SPattern{sparkle}},
+		},
+	}
+	testFrame(t, p, expectation{0, Frame{bg, {0xFF, 0xFF, 0xFF}, bg}})
+	p.Layers[1].Blend = BlendAdd
+	testFrame(t, p, expectation{0, Frame{bg, {0xFF, 0xFF, 0xFF}, bg}})
+	p.Layers[1].Blend = BlendMultiply
+	testFrame(t, p, expectation{0, Frame{{}, bg, {}}})
+	p.Layers[1].Blend = BlendNormal
+	p.Layers[1].Transparency = SValue{Const(255)}
+	testFrame(t, p, expectation{0, Frame{bg, bg, bg}})
+	p.Layers[1].Transparency = SValue{Const(128)}
+	testFrame(t, p, expectation{0, Frame{bg, {0x9F, 0x9F, 0x9F}, bg}})
+
+	// Explicit coverage draws dark colors.
+	dark := Color{0x10, 0, 0}
+	p.Layers[1] = Layer{Pattern: SPattern{&dark}, Alpha: SPattern{Frame{{}, {0xFF, 0xFF, 0xFF}, {0x80, 0x80, 0x80}}}}
+	testFrame(t, p, expectation{0, Frame{bg, dark, {0x28, 0x20, 0x20}}})
+
+	// A layer that doesn't render all the pixels doesn't see the previous one.
+	red := Color{0x40, 0, 0}
+	green := Color{0, 0x40, 0}
+	p = &Layers{
+		Layers: []Layer{
+			{Pattern: SPattern{&red}},
+			{Pattern: SPattern{&Split{Left: SPattern{&green}, Offset: SValue{Const(1)}}}, Blend: BlendAdd},
+		},
+	}
+	testFrame(t, p, expectation{0, Frame{{0x40, 0x40, 0}, red, red}})
+}
+
+func TestMeter(t *testing.T) {
+	w := Color{0xFF, 0xFF, 0xFF}
+	p := &Meter{Value: SValue{&Equation{V: "max(0, 100 - t/10)"}}, Max: 100, PeakHoldMS: 200, PeakDecayMS: 1000}
+	e := []expectation{
+		{0, Frame{w, w, w, w, w, w, w, w}},
+		{300, Frame{w, w, w, w, w, {0x99, 0x99, 0x99}, {}, w}},
+		{500, Frame{w, w, w, w, {}, w, {}, {}}},
+		{900, Frame{{0xCC, 0xCC, 0xCC}, {}, w, {}, {}, {}, {}, {}}},
+		{1500, Frame{{}, {}, {}, {}, {}, {}, {}, {}}},
+	}
+	testFrames(t, p, e)
+	p.Center = true
+	testFrame(t, p, expectation{500, Frame{{}, w, w, w, w, w, w, {}}})
+	p.Center = false
+	p.Reverse = true
+	testFrame(t, p, expectation{500, Frame{{}, {}, w, {}, w, w, w, w}})
+
+	a := Color{0x10, 0x10, 0x10}
+	b := Color{0x20, 0x20, 0x20}
+	p = &Meter{Value: SValue{Const(64)}, Fill: SPattern{&a}, Empty: SPattern{&b}}
+	testFrame(t, p, expectation{0, Frame{a, b, b, b}})
+}
+
 func TestScale(t *testing.T) {
 	f := Frame{{0x60, 0x60, 0x60}, {0x10, 0x20, 0x30}}
 	p := &Scale{Child: SPattern{f}, Interpolation: NearestSkip, RatioMilli: SValue{Const(667)}}
 	expected := Frame{{0x60, 0x60, 0x60}, {}, {0x10, 0x20, 0x30}}
 	testFrame(t, p, expectation{0, expected})
+	// Defaults to Linear.
+	p = &Scale{Child: SPattern{Frame{{}, {0xFF, 0xFF, 0xFF}}}}
+	testFrame(t, p, expectation{0, Frame{{}, {0x40, 0x40, 0x40}, {0xC0, 0xC0, 0xC0}, {0xFF, 0xFF, 0xFF}}})
 }
@@ -0,0 +1,366 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// equation is a small integer expression language usable as a Value.
+
+package anim1d
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Equation is a value computed from an integer expression.
+//
+// The expression is compiled once, then evaluated without memory allocation.
+//
+// The variables are "t" for timeMS and "l" for the length of the strip.
+// Supported operators are + - * / % and unary -, with the usual precedence and
+// parenthesis. Supported functions are:
+//   - abs(v)
+//   - min(a, b) and max(a, b)
+//   - clamp(v, min, max)
+//   - sin(x, p), tri(x, p) and saw(x, p) which are periodic over p and return
+//     a value in [-255, 255]; they return 0 when p <= 0
+//
+// All calculations are done in int32. Division or modulo by zero returns 0.
+// The result of a modulo has the sign of the divisor so "t%1000" is always in
+// [0, 1000).
+//
+// Example of a breathing intensity with a period of 4 seconds:
+//
+//	"=128+sin(t,4000)/2"
+type Equation struct {
+	V    string // Expression, without the leading '='
+	prog []eqOp
+}
+
+// Eval implements Value.
+func (e *Equation) Eval(timeMS uint32, l int) int32 {
+	if e.prog == nil {
+		// Created directly and not through JSON. An invalid expression evaluates
+		// to 0.
+		prog, err := compileEquation(e.V)
+		if err != nil {
+			prog = []eqOp{{eqConst, 0}}
+		}
+		e.prog = prog
+	}
+	var stack [eqMaxStack]int32
+	n := 0
+	for _, op := range e.prog {
+		switch op.code {
+		case eqConst:
+			stack[n] = op.v
+			n++
+		case eqTime:
+			stack[n] = int32(timeMS)
+			n++
+		case eqLen:
+			stack[n] = int32(l)
+			n++
+		case eqNeg:
+			stack[n-1] = -stack[n-1]
+		case eqAbs:
+			if stack[n-1] < 0 {
+				stack[n-1] = -stack[n-1]
+			}
+		case eqClamp:
+			n -= 2
+			stack[n-1] = MinMax32(stack[n-1], stack[n], stack[n+1])
+		default:
+			n--
+			stack[n-1] = eqBinary(op.code, stack[n-1], stack[n])
+		}
+	}
+	return stack[0]
+}
+
+// Private details.
+
+type eqCode uint8
+
+const (
+	eqConst eqCode = iota
+	eqTime
+	eqLen
+	eqNeg
+	eqAbs
+	eqClamp
+	eqAdd
+	eqSub
+	eqMul
+	eqDiv
+	eqMod
+	eqMin
+	eqMax
+	eqSin
+	eqTri
+	eqSaw
+)
+
+// eqOp is one instruction of a compiled Equation. The program is in reverse
+// polish notation.
+type eqOp struct {
+	code eqCode
+	v    int32
+}
+
+// eqMaxStack is the maximum stack depth of a compiled Equation.
+const eqMaxStack = 16
+
+// eqFuncs are the functions supported in an Equation.
+var eqFuncs = map[string]struct {
+	code eqCode
+	args int
+}{
+	"abs":   {eqAbs, 1},
+	"clamp": {eqClamp, 3},
+	"max":   {eqMax, 2},
+	"min":   {eqMin, 2},
+	"saw":   {eqSaw, 2},
+	"sin":   {eqSin, 2},
+	"tri":   {eqTri, 2},
+}
+
+func eqBinary(code eqCode, a, b int32) int32 {
+	switch code {
+	case eqAdd:
+		return a + b
+	case eqSub:
+		return a - b
+	case eqMul:
+		return a * b
+	case eqDiv:
+		if b == 0 {
+			return 0
+		}
+		return a / b
+	case eqMod:
+		return eqModulo(a, b)
+	case eqMin:
+		if a < b {
+			return a
+		}
+		return b
+	case eqMax:
+		if a > b {
+			return a
+		}
+		return b
+	case eqSin, eqTri, eqSaw:
+		return eqWave(code, a, b)
+	default:
+		return 0
+	}
+}
+
+// eqModulo returns a modulo b with the sign of b.
+func eqModulo(a, b int32) int32 {
+	if b == 0 {
+		return 0
+	}
+	m := a % b
+	if m != 0 && (m < 0) != (b < 0) {
+		m += b
+	}
+	return m
+}
+
+// eqWave evaluates the periodic function code at x over the period p.
+func eqWave(code eqCode, x, p int32) int32 {
+	if p <= 0 {
+		return 0
+	}
+	phase := uint16(int64(eqModulo(x, p)) * 65536 / int64(p))
+	switch code {
+	case eqSin:
+		return wave255(sin16(phase))
+	case eqTri:
+		return wave255(tri16(phase))
+	default:
+		return wave255(saw16(phase))
+	}
+}
+
+// wave255 scales a wave in [-32767, 32767] to [-255, 255].
+func wave255(v int32) int32 {
+	return (v*255 + 16384) >> 15
+}
+
+// compileEquation compiles the expression to a program.
+func compileEquation(s string) ([]eqOp, error) {
+	p := eqParser{s: s}
+	p.skipSpaces()
+	if p.i == len(p.s) {
+		return []eqOp{{eqConst, 0}}, nil
+	}
+	if err := p.expr(); err != nil {
+		return nil, err
+	}
+	if p.i != len(p.s) {
+		return nil, fmt.Errorf("equation: unexpected %q at offset %d", p.s[p.i:], p.i)
+	}
+	return p.prog, nil
+}
+
+// eqParser is a recursive descent parser.
+type eqParser struct {
+	s     string
+	i     int
+	prog  []eqOp
+	depth int
+}
+
+func (p *eqParser) expr() error {
+	if err := p.term(); err != nil {
+		return err
+	}
+	for p.i < len(p.s) {
+		var code eqCode
+		switch p.s[p.i] {
+		case '+':
+			code = eqAdd
+		case '-':
+			code = eqSub
+		default:
+			return nil
+		}
+		p.next()
+		if err := p.term(); err != nil {
+			return err
+		}
+		p.emit(code, 0, 2)
+	}
+	return nil
+}
+
+func (p *eqParser) term() error {
+	if err := p.unary(); err != nil {
+		return err
+	}
+	for p.i < len(p.s) {
+		var code eqCode
+		switch p.s[p.i] {
+		case '*':
+			code = eqMul
+		case '/':
+			code = eqDiv
+		case '%':
+			code = eqMod
+		default:
+			return nil
+		}
+		p.next()
+		if err := p.unary(); err != nil {
+			return err
+		}
+		p.emit(code, 0, 2)
+	}
+	return nil
+}
+
+func (p *eqParser) unary() error {
+	if p.i < len(p.s) && p.s[p.i] == '-' {
+		p.next()
+		if err := p.unary(); err != nil {
+			return err
+		}
+		p.emit(eqNeg, 0, 1)
+		return nil
+	}
+	return p.primary()
+}
+
+func (p *eqParser) primary() error {
+	if p.i == len(p.s) {
+		return errors.New("equation: unexpected end of expression")
+	}
+	start := p.i
+	c := p.s[p.i]
+	switch {
+	case c == '(':
+		p.next()
+		if err := p.expr(); err != nil {
+			return err
+		}
+		return p.expect(')')
+	case c >= '0' && c <= '9':
+		for p.i < len(p.s) && p.s[p.i] >= '0' && p.s[p.i] <= '9' {
+			p.i++
+		}
+		v, err := strconv.ParseInt(p.s[start:p.i], 10, 32)
+		if err != nil {
+			return fmt.Errorf("equation: %w", err)
+		}
+		p.skipSpaces()
+		return p.emit(eqConst, int32(v), 0)
+	case c >= 'a' && c <= 'z':
+		for p.i < len(p.s) && p.s[p.i] >= 'a' && p.s[p.i] <= 'z' {
+			p.i++
+		}
+		name := p.s[start:p.i]
+		p.skipSpaces()
+		switch name {
+		case "t":
+			return p.emit(eqTime, 0, 0)
+		case "l":
+			return p.emit(eqLen, 0, 0)
+		}
+		f, ok := eqFuncs[name]
+		if !ok {
+			return fmt.Errorf("equation: unknown identifier %q", name)
+		}
+		if err := p.expect('('); err != nil {
+			return err
+		}
+		for j := 0; j < f.args; j++ {
+			if j != 0 {
+				if err := p.expect(','); err != nil {
+					return err
+				}
+			}
+			if err := p.expr(); err != nil {
+				return err
+			}
+		}
+		if err := p.expect(')'); err != nil {
+			return err
+		}
+		return p.emit(f.code, 0, f.args)
+	default:
+		return fmt.Errorf("equation: unexpected %q at offset %d", c, p.i)
+	}
+}
+
+// emit appends an instruction that pops 'pops' values and pushes one.
+func (p *eqParser) emit(code eqCode, v int32, pops int) error {
+	p.prog = append(p.prog, eqOp{code, v})
+	p.depth += 1 - pops
+	if p.depth > eqMaxStack {
+		return errors.New("equation: expression is too complex")
+	}
+	return nil
+}
+
+func (p *eqParser) expect(c byte) error {
+	if p.i == len(p.s) || p.s[p.i] != c {
+		return fmt.Errorf("equation: expected %q at offset %d", c, p.i)
+	}
+	p.next()
+	return nil
+}
+
+// next skips the current character and the following spaces.
+func (p *eqParser) next() {
+	p.i++
+	p.skipSpaces()
+}
+
+func (p *eqParser) skipSpaces() {
+	for p.i < len(p.s) && p.s[p.i] == ' ' {
+		p.i++
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package anim1d
+
+import "testing"
+
+func TestEquation(t *testing.T) {
+	data := []struct {
+		v        string
+		timeMS   uint32
+		l        int
+		expected int32
+	}{
+		{"", 10, 0, 0},
+		{"42", 10, 0, 42},
+		{"t", 10, 0, 10},
+		{"l", 10, 20, 20},
+		{"1+2*3", 0, 0, 7},
+		{"(1+2)*3", 0, 0, 9},
+		{"10-2-3", 0, 0, 5},
+		{"-t", 10, 0, -10},
+		{"--t", 10, 0, 10},
+		{"t/3", 10, 0, 3},
+		{"t/0", 10, 0, 0},
+		{"t%3", 10, 0, 1},
+		{"-t%3", 10, 0, 2},
+		{"t%0", 10, 0, 0},
+		{"min(t, l)", 10, 5, 5},
+		{"max(t, l)", 10, 5, 10},
+		{"clamp(t, 0, 5)", 10, 0, 5},
+		{"abs(l-t)", 10, 5, 5},
+		{"sin(t, 1000)", 0, 0, 0},
+		{"sin(t, 1000)", 250, 0, 255},
+		{"sin(t, 1000)", 750, 0, -255},
+		{"sin(t, 1000)", 1000, 0, 0},
+		{"tri(t, 1000)", 125, 0, 128},
+		{"tri(t, 1000)", 250, 0, 255},
+		{"saw(t, 1000)", 500, 0, 0},
+		{"sin(t, 0)", 500, 0, 0},
+		{"tri(t, 0)", 500, 0, 0},
+		{"saw(t, 0)", 500, 0, 0},
+		{"saw(t, -10)", 500, 0, 0},
+		{"128 + sin(t, 4000) / 2", 1000, 0, 255},
+	}
+	for i, line := range data {
+		e := Equation{V: line.v}
+		if v := e.Eval(line.timeMS, line.l); v != line.expected {
+			t.Fatalf("%d: %q.Eval(%d, %d) = %d, expected %d", i, line.v, line.timeMS, line.l, v, line.expected)
+		}
+	}
+}
+
+func TestEquation_invalid(t *testing.T) {
+	e := Equation{V: "t+"}
+	if v := e.Eval(10, 0); v != 0 {
+		t.Fatal(v)
+	}
+	e = Equation{V: "1+(1+(1+(1+(1+(1+(1+(1+(1+(1+(1+(1+(1+(1+(1+(1+(1+1)))))))))))))))"}
+	if _, err := compileEquation(e.V); err == nil {
+		t.Fatal("expected too complex")
+	}
+}
+
+func TestEquation_allocs(t *testing.T) {
+	e := Equation{V: "clamp(128 + sin(t, 4000) / 2 + tri(t, l) - saw(t, 300) % 7, 0, 255)"}
+	e.Eval(0, 100)
+	if n := testing.AllocsPerRun(100, func() { e.Eval(1234, 100) }); n != 0 {
+		t.Fatalf("%g allocations", n)
+	}
+}
+
+func BenchmarkEquation(b *testing.B) {
+	e := Equation{V: "128 + sin(t, 4000) / 2"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		e.Eval(uint32(i), 100)
+	}
+}
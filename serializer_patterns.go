@@ -22,26 +22,49 @@ var patternsLookup map[string]reflect.Type
 var knownPatterns = []Pattern{
 	// Patterns
 	&Color{},
+	&HSV{},
 	&Frame{},
+	&Palette{},
 	&Rainbow{},
 	&Repeated{},
+	&HueCycle{},
 	&Aurore{},
 	&NightStars{},
+	&Twinkle{},
+	&Comet{},
+	&Fire{},
+	&Noise{},
 	&Lightning{},
+	&Thunderstorm{},
 	&WishingStar{},
 	// Mixers
 	&Gradient{},
+	&MultiGradient{},
 	&Split{},
 	&Transition{},
 	&Loop{},
+	&Sequence{},
 	&Chronometer{},
 	&Rotate{},
 	&PingPong{},
 	&Crop{},
 	&Subset{},
+	&Reverse{},
+	&Mirror{},
+	&Kaleidoscope{},
+	&TimeShift{},
+	&TimeScale{},
+	&Freeze{},
+	&TimeLoop{},
+	&Delay{},
 	&Dim{},
+	&Mask{},
+	&MapPalette{},
+	&HueShift{},
 	&Add{},
+	&Layers{},
 	&Scale{},
+	&Meter{},
 }
 
 func init() {
@@ -111,6 +134,22 @@ func (c *Color) MarshalJSON() ([]byte, error) {
 	return json.Marshal(c.String())
 }
 
+// UnmarshalJSON decodes the string "hsv(H,S%,V%)" to the color.
+//
+// If unmarshalling fails, 'h' is not touched.
+func (h *HSV) UnmarshalJSON(b []byte) error {
+	s, err := jsonUnmarshalString(b)
+	if err != nil {
+		return err
+	}
+	return h.FromString(s)
+}
+
+// MarshalJSON encodes the color as a string "hsv(H,S%,V%)".
+func (h *HSV) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.String())
+}
+
 // UnmarshalJSON decodes the string "LRRGGBB..." to the colors.
 //
 // If unmarshalling fails, 'f' is not touched.
@@ -127,6 +166,22 @@ func (f Frame) MarshalJSON() ([]byte, error) {
 	return json.Marshal(f.String())
 }
 
+// UnmarshalJSON decodes the string "P#RRGGBB P%,..." to the palette.
+//
+// If unmarshalling fails, 'p' is not touched.
+func (p *Palette) UnmarshalJSON(b []byte) error {
+	s, err := jsonUnmarshalString(b)
+	if err != nil {
+		return err
+	}
+	return p.FromString(s)
+}
+
+// MarshalJSON encodes the palette as a string "P#RRGGBB P%,...".
+func (p *Palette) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
 // UnmarshalJSON decodes the string "Rainbow" to the rainbow.
 func (r *Rainbow) UnmarshalJSON(b []byte) error {
 	s, err := jsonUnmarshalString(b)
@@ -214,6 +269,16 @@ func parsePatternString(b []byte) (Pattern, error) {
 			var f Frame
 			err := json.Unmarshal(b, &f)
 			return f, err
+		case 'P':
+			// "P#RRGGBB P%,..."
+			p := &Palette{}
+			err := json.Unmarshal(b, p)
+			return p, err
+		case 'h':
+			// "hsv(H,S%,V%)"
+			h := &HSV{}
+			err := json.Unmarshal(b, h)
+			return h, err
 		case rainbowKey[0]:
 			// "Rainbow"
 			r := &Rainbow{}
@@ -221,5 +286,5 @@ func parsePatternString(b []byte) (Pattern, error) {
 			return r, err
 		}
 	}
-	return nil, errors.New("unrecognized pattern string, should start with '#', 'L' or be a known constant")
+	return nil, errors.New("unrecognized pattern string, should start with '#', 'L', 'P', 'hsv(' or be a known constant")
 }
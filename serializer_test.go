@@ -7,7 +7,9 @@ package anim1d
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -28,7 +30,7 @@ func TestJSONPatterns(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		if isColorOrFrameOrRainbow(p) {
+		if isStringPattern(p) {
 			if c := b[0]; c != uint8('"') {
 				t.Fatalf("Expected '\"', got %q", c)
 			}
@@ -52,8 +54,14 @@ func TestJSONPatternsSpotCheck(t *testing.T) {
 	serializePattern(t, &Frame{}, `"L"`)
 	serializePattern(t, &Frame{{1, 2, 3}, {4, 5, 6}}, `"L010203040506"`)
 	serializePattern(t, &Rainbow{}, `"Rainbow"`)
-	serializePattern(t, &PingPong{}, `{"Child":{},"MovePerHour":0,"_type":"PingPong"}`)
+	serializePattern(t, &HSV{36409, 204, 255}, `"hsv(200,80%,100%)"`)
+	serializePattern(t, &Palette{}, `"P"`)
+	serializePattern(t, &Palette{Stops: []PaletteStop{{Color{0xFF, 0, 0}, 0}, {Color{0, 0xFF, 0}, 16384}, {Color{0, 0, 0xFF}, 65535}}, Wrap: true}, `"Pwrap:#ff0000,#00ff00 25%,#0000ff"`)
+	serializePattern(t, &MapPalette{Palette: Palette{Stops: []PaletteStop{{Color{}, 0}, {Color{0xFF, 0xFF, 0xFF}, 65535}}}}, `{"Channel":"","Child":{},"Palette":"P#000000,#ffffff","Value":0,"_type":"MapPalette"}`)
+	serializePattern(t, &PingPong{}, `{"Child":{},"Interpolation":"","MovePerHour":0,"_type":"PingPong"}`)
 	serializePattern(t, &Chronometer{}, `{"Child":{},"_type":"Chronometer"}`)
+	serializePattern(t, &Layers{Layers: []Layer{{Pattern: SPattern{&Color{}}, Blend: BlendScreen, Transparency: SValue{Const(128)}}}}, `{"Layers":[{"Alpha":{},"Blend":"screen","Pattern":"#000000","Transparency":128}],"_type":"Layers"}`)
+	serializePattern(t, &MultiGradient{Stops: []GradientStop{{Pattern: SPattern{&Color{}}, Position: SValue{Const(1)}}}}, `{"LinearLight":false,"Stops":[{"Curve":"","Pattern":"#000000","Position":1}],"_type":"MultiGradient"}`)
 
 	// Create one more complex. Assert that int64 is not mangled.
 	p := &Transition{
@@ -70,10 +78,122 @@ func TestJSONPatternsSpotCheck(t *testing.T) {
 		TransitionMS: 600000,
 		Curve:        Direct,
 	}
-	expected := `{"After":"#000000","Before":{"After":"#ffffff","Before":{},"Curve":"direct","OffsetMS":600000,"TransitionMS":600000,"_type":"Transition"},"Curve":"direct","OffsetMS":1800000,"TransitionMS":600000,"_type":"Transition"}`
+	expected := `{"After":"#000000","Before":{"After":"#ffffff","Before":{},"Curve":"direct","Effect":"","OffsetMS":600000,"TransitionMS":600000,"_type":"Transition"},"Curve":"direct","Effect":"","OffsetMS":1800000,"TransitionMS":600000,"_type":"Transition"}`
 	serializePattern(t, p, expected)
 }
 
+func TestJSONRender(t *testing.T) {
+	// Fields left to their zero value must render the same after a round trip.
+	bg := Color{0x40, 0x40, 0x40}
+	data := []Pattern{
+		&Layers{Layers: []Layer{{Pattern: SPattern{&bg}}, {Pattern: SPattern{Frame{{}, {0xFF, 0xFF, 0xFF}}}}}},
+		&Layers{Layers: []Layer{{Pattern: SPattern{&bg}}, {Pattern: SPattern{&Color{}}, Alpha: SPattern{&Rainbow{}}}}},
+		&Mirror{Child: SPattern{&Rainbow{}}},
+		&Kaleidoscope{Child: SPattern{&Rainbow{}}},
+		&Aurore{},
+		&NightStars{Density: SValue{Const(128)}},
+		&Twinkle{Density: SValue{Const(128)}},
+		&Comet{Heads: []CometHead{{Color: Color{0xFF, 0, 0}, MovePerHour: MovePerHour{Const(3600 * 4)}, TailLength: SValue{Const(3)}}}},
+		&Fire{},
+		&Noise{},
+	}
+	for i, p := range data {
+		b := marshalPattern(p)
+		var p2 SPattern
+		if err := json.Unmarshal(b, &p2); err != nil {
+			t.Fatalf("%d: %s, %v", i, b, err)
+		}
+		for _, timeMS := range []uint32{0, 250, 1000, 5000} {
+			expected := make(Frame, 10)
+			p.Render(expected, timeMS)
+			actual := make(Frame, 10)
+			p2.Render(actual, timeMS)
+			if !expected.isEqual(actual) {
+				t.Fatalf("%d: %s @%d: %v != %v", i, b, timeMS, actual, expected)
+			}
+		}
+	}
+}
+
+func TestJSONCurve(t *testing.T) {
+	var c Curve
+	for _, s := range []string{`""`, `"ease-out"`, `"steps(1,middle)"`, `"cubic-bezier(0.1, 0.7, 1.0, 0.1)"`, `"steps(5, jump-both)"`} {
+		if err := json.Unmarshal([]byte(s), &c); err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+	}
+	for _, s := range []string{`"bouncy"`, `"cubic-bezier(0.1,0.7,1.0)"`, `"cubic-bezier(-1,0,1,1)"`, `"cubic-bezier(nan,0,1,1)"`, `"cubic-bezier(0.1,inf,1,1)"`, `"cubic-bezier(0.1,0,1,-Inf)"`, `"steps(0)"`, `"steps(1,jump-none)"`, `"steps(2,middle)"`} {
+		if err := json.Unmarshal([]byte(s), &c); err == nil {
+			t.Fatalf("%s should have failed", s)
+		}
+	}
+}
+
+func TestJSONEnums(t *testing.T) {
+	data := []struct {
+		v        interface{}
+		good     string
+		bad      string
+		expected interface{}
+	}{
+		{new(Effect), `"wipe-left"`, `"slide"`, WipeLeft},
+		{new(Blend), `"screen"`, `"overlay"`, BlendScreen},
+		{new(Channel), `"green"`, `"alpha"`, ChannelGreen},
+		{new(NoiseKind), `"simplex"`, `"worley"`, NoiseSimplex},
+	}
+	for i, line := range data {
+		if err := json.Unmarshal([]byte(line.good), line.v); err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if v := reflect.ValueOf(line.v).Elem().Interface(); v != line.expected {
+			t.Fatalf("%d: %q != %q", i, v, line.expected)
+		}
+		// On failure, the value is not touched.
+		if err := json.Unmarshal([]byte(line.bad), line.v); err == nil {
+			t.Fatalf("%d: %s should have failed", i, line.bad)
+		}
+		if v := reflect.ValueOf(line.v).Elem().Interface(); v != line.expected {
+			t.Fatalf("%d: %q != %q", i, v, line.expected)
+		}
+	}
+}
+
+func TestJSONEquation(t *testing.T) {
+	var s SValue
+	if err := json.Unmarshal([]byte(`"=t*2+l"`), &s); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.Eval(10, 3); v != 23 {
+		t.Fatalf("%d", v)
+	}
+	if err := json.Unmarshal([]byte(`{"V":"t/2","_type":"Equation"}`), &s); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.Eval(10, 3); v != 5 {
+		t.Fatalf("%d", v)
+	}
+	for _, bad := range []string{`"=t+"`, `"=foo(1)"`, `"=(t"`, `"=min(1)"`, `"=t t"`} {
+		if err := json.Unmarshal([]byte(bad), &s); err == nil {
+			t.Fatalf("%s should have failed", bad)
+		}
+	}
+}
+
+func TestJSONOscillator(t *testing.T) {
+	var s SValue
+	if err := json.Unmarshal([]byte(`"saw(100, 10)"`), &s); err != nil {
+		t.Fatal(err)
+	}
+	if o := s.Value.(*Oscillator); *o != (Oscillator{Wave: Sawtooth, PeriodMS: 100, Min: 10, Max: 255}) {
+		t.Fatalf("%#v", o)
+	}
+	for _, bad := range []string{`"sin(a)"`, `"sin(1"`, `"sin(-1)"`, `"sin(1,2,3,4,5)"`, `"sinus(1)"`} {
+		if err := json.Unmarshal([]byte(bad), &s); err == nil {
+			t.Fatalf("%s should have failed", bad)
+		}
+	}
+}
+
 func TestJSONValues(t *testing.T) {
 	for _, v := range knownValues {
 		v2 := &SValue{v}
@@ -103,6 +223,14 @@ func TestJSONValues(t *testing.T) {
 			if _, err = fmt.Sscanf(string(b), "\"%%%d\"", &i); err != nil {
 				t.Fatalf("%v", err)
 			}
+		} else if isOscillator(v) {
+			if !strings.HasPrefix(string(b), "\"sin(") {
+				t.Fatalf("Expected '\"sin(', got %s", b)
+			}
+		} else if isEquation(v) {
+			if !strings.HasPrefix(string(b), "\"=") {
+				t.Fatalf("Expected '\"=', got %s", b)
+			}
 		} else if isRand(v) && string(b) == "\""+randKey+"\"" {
 			// Ok.
 		} else {
@@ -131,6 +259,12 @@ func TestJSONValuesSpotCheck(t *testing.T) {
 	serializeValue(t, &p, `"-10%"`)
 	serializeValue(t, &Rand{}, `"rand"`)
 	serializeValue(t, &Rand{TickMS: 43}, `{"TickMS":43,"_type":"Rand"}`)
+	serializeValue(t, &Equation{V: "t%1000"}, `"=t%1000"`)
+	serializeValue(t, &Oscillator{Wave: Sine, PeriodMS: 1000, Max: 255}, `"sin(1000)"`)
+	serializeValue(t, &Oscillator{Wave: Square, PeriodMS: 1000, Min: -10, Max: 10}, `"square(1000,-10,10)"`)
+	serializeValue(t, &Oscillator{Wave: Triangle, PeriodMS: 1000, Max: 255, PhaseMS: 250}, `"tri(1000,0,255,250)"`)
+	serializeValue(t, &Keyframes{Frames: []Keyframe{{0, 0, Direct}, {1000, 255, ""}}, Mode: KeyframesLoop}, `{"Frames":[{"Curve":"direct","TimeMS":0,"Value":0},{"Curve":"","TimeMS":1000,"Value":255}],"Mode":"loop","_type":"Keyframes"}`)
+	serializeValue(t, &Equation{V: "128 + sin(t, 4000) / 2"}, `"=128 + sin(t, 4000) / 2"`)
 }
 
 //
@@ -150,10 +284,16 @@ func serializePattern(t *testing.T, p Pattern, expected string) {
 	}
 }
 
-func isColorOrFrameOrRainbow(p Pattern) bool {
+func isStringPattern(p Pattern) bool {
 	if _, ok := p.(*Color); ok {
 		return ok
 	}
+	if _, ok := p.(*HSV); ok {
+		return ok
+	}
+	if _, ok := p.(*Palette); ok {
+		return ok
+	}
 	if _, ok := p.(*Frame); ok {
 		return ok
 	}
@@ -196,6 +336,16 @@ func isOpMod(v Value) bool {
 	return ok
 }
 
+func isOscillator(v Value) bool {
+	_, ok := v.(*Oscillator)
+	return ok
+}
+
+func isEquation(v Value) bool {
+	_, ok := v.(*Equation)
+	return ok
+}
+
 func isRand(v Value) bool {
 	_, ok := v.(*Rand)
 	return ok
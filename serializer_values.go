@@ -25,6 +25,9 @@ var knownValues = []Value{
 	&OpMod{},
 	&OpStep{},
 	&Rand{},
+	&Equation{},
+	&Keyframes{},
+	&Oscillator{},
 }
 
 func init() {
@@ -99,6 +102,20 @@ func (s *SValue) UnmarshalJSON(b []byte) error {
 			}
 			return err
 		}
+		if isOscillatorString(v) {
+			var o Oscillator
+			if err = o.UnmarshalJSON(b); err == nil {
+				s.Value = &o
+			}
+			return err
+		}
+		if strings.HasPrefix(v, "=") {
+			var e Equation
+			if err = e.UnmarshalJSON(b); err == nil {
+				s.Value = &e
+			}
+			return err
+		}
 		return fmt.Errorf("unknown value %q", v)
 	}
 	o, err := jsonUnmarshalWithType(b, valuesLookup, nil)
@@ -254,6 +271,125 @@ func (r *Rand) MarshalJSON() ([]byte, error) {
 	return jsonMarshalWithTypeName(r2, "Rand")
 }
 
+// UnmarshalJSON decodes the equation in the form of a string "=expression"
+// and compiles it.
+//
+// If unmarshalling fails, 'e' is not touched.
+func (e *Equation) UnmarshalJSON(b []byte) error {
+	s, err := jsonUnmarshalString(b)
+	if err == nil {
+		if !strings.HasPrefix(s, "=") {
+			return errors.New("equation: must start with =")
+		}
+		s = s[1:]
+	} else {
+		// Also accept the dict form.
+		type tmpEquation Equation
+		var e2 tmpEquation
+		if err := json.Unmarshal(b, &e2); err != nil {
+			return err
+		}
+		s = e2.V
+	}
+	prog, err := compileEquation(s)
+	if err != nil {
+		return err
+	}
+	e.V = s
+	e.prog = prog
+	return nil
+}
+
+// MarshalJSON encodes the equation as its original string.
+func (e *Equation) MarshalJSON() ([]byte, error) {
+	return json.Marshal("=" + e.V)
+}
+
+// UnmarshalJSON decodes the oscillator in the form of a string
+// "wave(period,min,max,phase)".
+//
+// If unmarshalling fails, 'o' is not touched.
+func (o *Oscillator) UnmarshalJSON(b []byte) error {
+	s, err := jsonUnmarshalString(b)
+	if err != nil {
+		return err
+	}
+	i := strings.IndexByte(s, '(')
+	if i == -1 || !strings.HasSuffix(s, ")") {
+		return errors.New("oscillator: must be in the form wave(period,min,max,phase)")
+	}
+	o2 := Oscillator{Wave: Wave(s[:i]), Max: 255}
+	switch o2.Wave {
+	case Sine, Triangle, Sawtooth, Square:
+	default:
+		return fmt.Errorf("oscillator: unknown wave %q", o2.Wave)
+	}
+	args := strings.Split(s[i+1:len(s)-1], ",")
+	if len(args) > 4 {
+		return errors.New("oscillator: too many arguments")
+	}
+	for j, a := range args {
+		v, err := strconv.ParseInt(strings.TrimSpace(a), 10, 32)
+		if err != nil {
+			return fmt.Errorf("oscillator: %w", err)
+		}
+		switch j {
+		case 0:
+			if v < 0 {
+				return errors.New("oscillator: period must be positive")
+			}
+			o2.PeriodMS = uint32(v)
+		case 1:
+			o2.Min = int32(v)
+		case 2:
+			o2.Max = int32(v)
+		case 3:
+			if v < 0 {
+				return errors.New("oscillator: phase must be positive")
+			}
+			o2.PhaseMS = uint32(v)
+		}
+	}
+	*o = o2
+	return nil
+}
+
+// MarshalJSON encodes the oscillator as a string, omitting trailing default
+// arguments.
+func (o *Oscillator) MarshalJSON() ([]byte, error) {
+	w := o.Wave
+	if w == "" {
+		w = Sine
+	}
+	args := []int64{int64(o.PeriodMS), int64(o.Min), int64(o.Max), int64(o.PhaseMS)}
+	n := 4
+	if o.PhaseMS == 0 {
+		n = 3
+		if o.Min == 0 && o.Max == 255 {
+			n = 1
+		}
+	}
+	out := string(w) + "("
+	for j := 0; j < n; j++ {
+		if j != 0 {
+			out += ","
+		}
+		out += strconv.FormatInt(args[j], 10)
+	}
+	return json.Marshal(out + ")")
+}
+
+// isOscillatorString returns true if the string looks like a serialized
+// Oscillator.
+func isOscillatorString(s string) bool {
+	for _, w := range []Wave{Sine, Triangle, Sawtooth, Square} {
+		if strings.HasPrefix(s, string(w)+"(") {
+			return true
+		}
+	}
+	return false
+}
+
 // UnmarshalJSON is because MovePerHour is a superset of SValue.
 func (m *MovePerHour) UnmarshalJSON(b []byte) error {
 	var s SValue
@@ -269,3 +405,82 @@ func (m *MovePerHour) MarshalJSON() ([]byte, error) {
 	s := SValue{m.Value}
 	return s.MarshalJSON()
 }
+
+// UnmarshalJSON decodes the curve and rejects invalid ones.
+//
+// If unmarshalling fails, 'c' is not touched.
+func (c *Curve) UnmarshalJSON(b []byte) error {
+	s, err := jsonUnmarshalString(b)
+	if err != nil {
+		return err
+	}
+	switch Curve(s) {
+	case Ease, EaseIn, EaseInOut, EaseOut, "", Direct, StepStart, StepMiddle, StepEnd, Bounce, Elastic, Back, Spring:
+	default:
+		if Curve(s).def() == nil {
+			_, err := parseCurve(s)
+			return err
+		}
+	}
+	*c = Curve(s)
+	return nil
+}
+
+// UnmarshalJSON decodes the effect and rejects unknown ones.
+//
+// If unmarshalling fails, 'e' is not touched.
+func (e *Effect) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b, knownEffects, "effect")
+	if err == nil {
+		*e = v
+	}
+	return err
+}
+
+// UnmarshalJSON decodes the blend mode and rejects unknown ones.
+//
+// If unmarshalling fails, 'b' is not touched.
+func (b *Blend) UnmarshalJSON(d []byte) error {
+	v, err := unmarshalEnum(d, knownBlends, "blend")
+	if err == nil {
+		*b = v
+	}
+	return err
+}
+
+// UnmarshalJSON decodes the channel and rejects unknown ones.
+//
+// If unmarshalling fails, 'c' is not touched.
+func (c *Channel) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b, knownChannels, "channel")
+	if err == nil {
+		*c = v
+	}
+	return err
+}
+
+// UnmarshalJSON decodes the noise kind and rejects unknown ones.
+//
+// If unmarshalling fails, 'k' is not touched.
+func (k *NoiseKind) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b, knownNoiseKinds, "noise kind")
+	if err == nil {
+		*k = v
+	}
+	return err
+}
+
+// unmarshalEnum decodes a JSON string and returns it only if it is one of the
+// known values.
+func unmarshalEnum[T ~string](b []byte, known []T, what string) (T, error) {
+	s, err := jsonUnmarshalString(b)
+	if err != nil {
+		return "", err
+	}
+	for _, k := range known {
+		if T(s) == k {
+			return k, nil
+		}
+	}
+	return "", fmt.Errorf("unknown %s %q", what, s)
+}
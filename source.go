@@ -12,7 +12,9 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"math"
 	"strconv"
+	"strings"
 
 	"github.com/maruel/anim1d/math32"
 )
@@ -82,6 +84,24 @@ func (c *Color) Mix(d Color, gradient uint8) {
 	c.B = uint8(((uint16(c.B)+1)*grad1 + (uint16(d.B)+1)*grad) >> 8)
 }
 
+// MixLinear is like Mix except that the blending is done in linear light
+// instead of on the sRGB encoded values.
+//
+// This avoids the dark and muddy middle when blending two saturated colors.
+func (c *Color) MixLinear(d Color, gradient uint8) {
+	grad := uint32(gradient)
+	grad1 := 255 - grad
+	c.R = linearToSRGB[(uint32(sRGBToLinear[c.R])*grad1+uint32(sRGBToLinear[d.R])*grad)/255>>4]
+	c.G = linearToSRGB[(uint32(sRGBToLinear[c.G])*grad1+uint32(sRGBToLinear[d.G])*grad)/255>>4]
+	c.B = linearToSRGB[(uint32(sRGBToLinear[c.B])*grad1+uint32(sRGBToLinear[d.B])*grad)/255>>4]
+}
+
+// Luminance returns the perceived brightness of the color, using the Rec. 709
+// coefficients on the encoded values.
+func (c *Color) Luminance() uint8 {
+	return uint8((54*uint16(c.R) + 183*uint16(c.G) + 19*uint16(c.B) + 128) >> 8)
+}
+
 func (c *Color) String() string {
 	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
 }
@@ -104,10 +124,52 @@ func (c *Color) At(x, y int) color.Color {
 	return color.NRGBA{}
 }
 
-// FromString converts a "#RRGGBB" encoded string to a Color.
+// HSV converts the color to hue, saturation and value.
+func (c *Color) HSV() HSV {
+	r, g, b := int32(c.R), int32(c.G), int32(c.B)
+	max, min := r, r
+	if g > max {
+		max = g
+	}
+	if b > max {
+		max = b
+	}
+	if g < min {
+		min = g
+	}
+	if b < min {
+		min = b
+	}
+	d := max - min
+	if d == 0 {
+		return HSV{V: uint8(max)}
+	}
+	// Each sector is a sixth of the turn.
+	var h int32
+	switch max {
+	case r:
+		h = (g - b) * 65536 / (6 * d)
+	case g:
+		h = (b-r)*65536/(6*d) + 65536/3
+	default:
+		h = (r-g)*65536/(6*d) + 2*65536/3
+	}
+	return HSV{H: uint16(h), S: uint8((d*255 + max/2) / max), V: uint8(max)}
+}
+
+// FromString converts a "#RRGGBB" or "hsv(H,S%,V%)" encoded string to a
+// Color.
 //
 // 'c' is untouched in case of error.
 func (c *Color) FromString(s string) error {
+	if strings.HasPrefix(s, "hsv(") {
+		var h HSV
+		if err := h.FromString(s); err != nil {
+			return err
+		}
+		*c = h.ToRGB()
+		return nil
+	}
 	if len(s) != 7 || s[0] != '#' {
 		return errors.New("invalid color string")
 	}
@@ -141,6 +203,91 @@ func (c *Color) FromRGBString(s string) error {
 
 //
 
+// HSV shows a single color expressed as hue, saturation and value on all
+// lights.
+//
+// It serializes as "hsv(H,S%,V%)" with H in degrees, e.g. "hsv(200,80%,100%)".
+type HSV struct {
+	H uint16 // Hue, 65536 is a full turn; 0 is red, 21845 is green, 43691 is blue
+	S uint8  // Saturation, 0 is grey and 255 is the pure color
+	V uint8  // Value, 0 is black
+}
+
+// Render implements Pattern.
+func (h *HSV) Render(pixels Frame, timeMS uint32) {
+	c := h.ToRGB()
+	for i := range pixels {
+		pixels[i] = c
+	}
+}
+
+// ToRGB converts the color to RGB using integer arithmetic.
+func (h *HSV) ToRGB() Color {
+	v := uint32(h.V)
+	if h.S == 0 {
+		return Color{h.V, h.V, h.V}
+	}
+	s := uint32(h.S)
+	h6 := uint32(h.H) * 6
+	// f is the position inside the sector.
+	f := (h6 & 0xffff) >> 8
+	p := uint8((v*(255-s) + 127) / 255)
+	q := uint8((v*(255*255-s*f) + 32512) / (255 * 255))
+	t := uint8((v*(255*255-s*(255-f)) + 32512) / (255 * 255))
+	switch h6 >> 16 {
+	case 0:
+		return Color{h.V, t, p}
+	case 1:
+		return Color{q, h.V, p}
+	case 2:
+		return Color{p, h.V, t}
+	case 3:
+		return Color{p, q, h.V}
+	case 4:
+		return Color{t, p, h.V}
+	default:
+		return Color{h.V, p, q}
+	}
+}
+
+func (h *HSV) String() string {
+	return fmt.Sprintf("hsv(%d,%d%%,%d%%)", (uint32(h.H)*360+32768)>>16, (uint32(h.S)*100+127)/255, (uint32(h.V)*100+127)/255)
+}
+
+// FromString converts a "hsv(H,S%,V%)" encoded string to a HSV.
+//
+// 'h' is untouched in case of error.
+func (h *HSV) FromString(s string) error {
+	if !strings.HasPrefix(s, "hsv(") || !strings.HasSuffix(s, ")") {
+		return errors.New("invalid hsv string")
+	}
+	args := strings.Split(s[4:len(s)-1], ",")
+	if len(args) != 3 {
+		return errors.New("invalid hsv string")
+	}
+	var v [3]float64
+	for i, a := range args {
+		a = strings.TrimSpace(a)
+		if i != 0 {
+			a = strings.TrimSuffix(a, "%")
+		}
+		f, err := strconv.ParseFloat(a, 32)
+		if err != nil {
+			return err
+		}
+		if f < 0 || (i != 0 && f > 100) {
+			return errors.New("invalid hsv string")
+		}
+		v[i] = f
+	}
+	h.H = uint16(int64(math.Floor(math.Mod(v[0], 360)*65536/360 + 0.5)))
+	h.S = uint8(math.Floor(v[1]*2.55 + 0.5))
+	h.V = uint8(math.Floor(v[2]*2.55 + 0.5))
+	return nil
+}
+
+//
+
 // Frame is a strip of colors. It knows how to renders itself into a frame
 // (which is recursive).
 type Frame []Color
@@ -181,6 +328,13 @@ func (f Frame) Mix(b Frame, gradient uint8) {
 	}
 }
 
+// MixLinear is like Mix except that the blending is done in linear light.
+func (f Frame) MixLinear(b Frame, gradient uint8) {
+	for i := range f {
+		f[i].MixLinear(b[i], gradient)
+	}
+}
+
 // ToRGB converts the Frame to a raw RGB stream.
 func (f Frame) ToRGB(b []byte) {
 	for i := range f {
@@ -262,6 +416,261 @@ func (f Frame) At(x, y int) color.Color {
 
 //
 
+// Gamma is an output stage transform that corrects each channel for the
+// non-linear brightness perception of the eye.
+//
+// Patterns work on linear 8 bits values, so fades look steppy at the low end
+// on LEDs. Apply the Gamma once on the final frame, right before Frame.ToRGB().
+type Gamma struct {
+	R, G, B [256]uint8
+}
+
+// MakeGamma returns a Gamma using the same exponent for all channels.
+//
+// 1 is the identity. 2.2 to 2.8 is common for LED strips.
+func MakeGamma(g float32) *Gamma {
+	return MakeGammaRGB(g, g, g)
+}
+
+// MakeGammaRGB returns a Gamma using an exponent per channel.
+//
+// This is useful to also correct for the color balance of the LEDs.
+func MakeGammaRGB(r, g, b float32) *Gamma {
+	out := &Gamma{}
+	for i := 0; i < 256; i++ {
+		x := float64(i) / 255
+		out.R[i] = uint8(math.Floor(255*math.Pow(x, float64(r)) + 0.5))
+		out.G[i] = uint8(math.Floor(255*math.Pow(x, float64(g)) + 0.5))
+		out.B[i] = uint8(math.Floor(255*math.Pow(x, float64(b)) + 0.5))
+	}
+	return out
+}
+
+// Apply corrects the frame in place.
+func (g *Gamma) Apply(f Frame) {
+	for i := range f {
+		f[i].R = g.R[f[i].R]
+		f[i].G = g.G[f[i].G]
+		f[i].B = g.B[f[i].B]
+	}
+}
+
+// sRGBToLinear converts an 8 bits sRGB value to a 16 bits linear light value.
+var sRGBToLinear [256]uint16
+
+// linearToSRGB converts a 12 bits linear light value to an 8 bits sRGB value.
+var linearToSRGB [4096]uint8
+
+func setupLinear() {
+	for i := range sRGBToLinear {
+		c := float64(i) / 255
+		if c <= 0.04045 {
+			c /= 12.92
+		} else {
+			c = math.Pow((c+0.055)/1.055, 2.4)
+		}
+		sRGBToLinear[i] = uint16(math.Floor(65535*c + 0.5))
+	}
+	for i := range linearToSRGB {
+		c := (float64(i) + 0.5) / 4096
+		if c <= 0.0031308 {
+			c *= 12.92
+		} else {
+			c = 1.055*math.Pow(c, 1/2.4) - 0.055
+		}
+		linearToSRGB[i] = uint8(math.Floor(255*c + 0.5))
+	}
+}
+
+func init() {
+	setupLinear()
+}
+
+//
+
+// PaletteStop is one color of a Palette.
+type PaletteStop struct {
+	Color    Color
+	Position uint16 // 0 is the beginning of the palette, 65535 the end
+}
+
+// Palette is a list of colors at positions, sampled with interpolation.
+//
+// As a Pattern, it renders itself stretched over the strip.
+//
+// It is serialized as a string "P#RRGGBB P%,#RRGGBB P%,...". The position is
+// optional; the first defaults to 0%, the last to 100% and the ones in between
+// are evenly spaced between their neighbors. Prefix the stops with "wrap:" to
+// set Wrap.
+type Palette struct {
+	Stops []PaletteStop // Must be ordered by Position
+	Wrap  bool          // Sampling between the last and the first stop blends them, instead of clamping
+}
+
+// Render implements Pattern.
+func (p *Palette) Render(pixels Frame, timeMS uint32) {
+	l := len(pixels)
+	for i := range pixels {
+		var pos int
+		if p.Wrap {
+			pos = i * 65536 / l
+		} else if l > 1 {
+			pos = i * 65535 / (l - 1)
+		}
+		pixels[i] = p.At(uint16(pos))
+	}
+}
+
+// At returns the color at position pos, interpolating between the stops.
+func (p *Palette) At(pos uint16) Color {
+	n := len(p.Stops)
+	if n == 0 {
+		return Color{}
+	}
+	// Index of the first stop after pos.
+	k := 0
+	for k < n && p.Stops[k].Position <= pos {
+		k++
+	}
+	var a, b *PaletteStop
+	var pa, pb, x int
+	switch {
+	case k == 0 || k == n:
+		if !p.Wrap {
+			if k == 0 {
+				return p.Stops[0].Color
+			}
+			return p.Stops[n-1].Color
+		}
+		// Between the last and the first stop, going through the end.
+		a, b = &p.Stops[n-1], &p.Stops[0]
+		pa, pb, x = int(a.Position), int(b.Position)+65536, int(pos)
+		if k == 0 {
+			x += 65536
+		}
+	default:
+		a, b = &p.Stops[k-1], &p.Stops[k]
+		pa, pb, x = int(a.Position), int(b.Position), int(pos)
+	}
+	if pb <= pa {
+		return a.Color
+	}
+	c := a.Color
+	c.Mix(b.Color, uint8(MinMax((x-pa)<<8/(pb-pa), 0, 255)))
+	return c
+}
+
+// At8 returns the color at the 8 bits index i.
+//
+// When Wrap is set, 256 wraps around to 0, otherwise 255 is the last stop.
+func (p *Palette) At8(i uint8) Color {
+	if p.Wrap {
+		return p.At(uint16(i) << 8)
+	}
+	return p.At(uint16(i) * 257)
+}
+
+func (p *Palette) String() string {
+	out := bytes.Buffer{}
+	out.WriteByte('P')
+	if p.Wrap {
+		out.WriteString("wrap:")
+	}
+	for i, s := range p.Stops {
+		if i != 0 {
+			out.WriteByte(',')
+		}
+		out.WriteString(s.Color.String())
+		if (i == 0 && s.Position == 0) || (i == len(p.Stops)-1 && s.Position == 65535) {
+			continue
+		}
+		v := strconv.FormatFloat(float64(s.Position)*100/65535, 'f', 3, 64)
+		v = strings.TrimRight(strings.TrimRight(v, "0"), ".")
+		out.WriteString(" " + v + "%")
+	}
+	return out.String()
+}
+
+// FromString converts a "P#RRGGBB P%,..." encoded string to a Palette.
+//
+// 'p' is untouched in case of error.
+func (p *Palette) FromString(s string) error {
+	if !strings.HasPrefix(s, "P") {
+		return errors.New("invalid palette string")
+	}
+	s = s[1:]
+	wrap := strings.HasPrefix(s, "wrap:")
+	if wrap {
+		s = s[len("wrap:"):]
+	}
+	if len(s) == 0 {
+		*p = Palette{Wrap: wrap}
+		return nil
+	}
+	// Split on the commas that are not within a "hsv(...)".
+	var items []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	items = append(items, s[start:])
+	stops := make([]PaletteStop, len(items))
+	// Positions, -1 when not specified.
+	pos := make([]int, len(items))
+	for i, item := range items {
+		item = strings.TrimSpace(item)
+		pos[i] = -1
+		if j := strings.LastIndexByte(item, ' '); j != -1 && strings.HasSuffix(item, "%") {
+			f, err := strconv.ParseFloat(item[j+1:len(item)-1], 64)
+			if err != nil {
+				return err
+			}
+			if f < 0 || f > 100 {
+				return errors.New("palette position must be between 0% and 100%")
+			}
+			pos[i] = int(math.Round(f * 65535 / 100))
+			item = strings.TrimSpace(item[:j])
+		}
+		if err := stops[i].Color.FromString(item); err != nil {
+			return err
+		}
+	}
+	if pos[0] == -1 {
+		pos[0] = 0
+	}
+	if last := len(pos) - 1; pos[last] == -1 {
+		pos[last] = 65535
+	}
+	for i := 1; i < len(pos); i++ {
+		if pos[i] == -1 {
+			// Spread evenly until the next specified position.
+			j := i + 1
+			for pos[j] == -1 {
+				j++
+			}
+			pos[i] = pos[i-1] + (pos[j]-pos[i-1])/(j-i+1)
+		}
+		if pos[i] < pos[i-1] {
+			return errors.New("palette positions must be in increasing order")
+		}
+	}
+	for i := range stops {
+		stops[i].Position = uint16(pos[i])
+	}
+	*p = Palette{Stops: stops, Wrap: wrap}
+	return nil
+}
+
 // Rainbow renders rainbow colors.
 type Rainbow struct {
 	// cached buffer for performance.
@@ -324,5 +733,582 @@ func waveLength2RGB(w int) (c Color) {
 	return
 }
 
+// HueCycle renders a cycle of hues over the strip.
+//
+// Unlike Rainbow, the saturation, value and span are configurable and the hues
+// can rotate over time.
+type HueCycle struct {
+	Saturation  SValue      // [0, 255]
+	Value       SValue      // [0, 255]
+	Span        SValue      // Degrees covered by the whole strip; 360 shows all hues once, 0 a single hue
+	MovePerHour MovePerHour // Rotation expressed in degrees per hour; 1296000 is a full cycle per second
+}
+
+// Render implements Pattern.
+func (h *HueCycle) Render(pixels Frame, timeMS uint32) {
+	l := len(pixels)
+	if l == 0 {
+		return
+	}
+	c := HSV{
+		S: uint8(MinMax32(h.Saturation.Eval(timeMS, l), 0, 255)),
+		V: uint8(MinMax32(h.Value.Eval(timeMS, l), 0, 255)),
+	}
+	// Both in 1/256th of degree.
+	start := int64(h.MovePerHour.Eval256(timeMS, l, 360))
+	span := int64(h.Span.Eval(timeMS, l)) * 256
+	for i := range pixels {
+		c.H = uint16((start + span*int64(i)/int64(l)) * 256 / 360)
+		pixels[i] = c.ToRGB()
+	}
+}
+
+// TODO(maruel): Create NightSky with:
+//    - Stars
+//    - WishingStar
+//    - Aurores
+//    - Super nova.
+//    - Rotation de la terre?
+//    - Station Internationale?
+
+// Aurore draws slowly drifting aurora curtains.
+//
+// The output only depends on the pixel index so it looks the same on strips
+// of any length.
+type Aurore struct {
+	Colors   Palette // Colors of the curtains, blended along the strip; defaults to green, teal and purple
+	Scale    SValue  // Width of a curtain in pixels; 0 (default) is 32
+	PeriodMS SValue  // Time for the curtains to drift by Scale; 0 (default) is 20000
+}
+
+var defaultAuroreColors = Palette{Stops: []PaletteStop{{Color{0x00, 0xFF, 0x40}, 0}, {Color{0x00, 0x80, 0xFF}, 32768}, {Color{0x80, 0x00, 0xFF}, 65535}}}
+
+// Render implements Pattern.
+func (a *Aurore) Render(pixels Frame, timeMS uint32) {
+	scale := MinMax32(a.Scale.Eval(timeMS, len(pixels)), 0, 65535)
+	if scale == 0 {
+		scale = 32
+	}
+	period := MinMax32(a.PeriodMS.Eval(timeMS, len(pixels)), 0, 1<<30)
+	if period == 0 {
+		period = 20000
+	}
+	colors := &a.Colors
+	if len(colors.Stops) == 0 {
+		colors = &defaultAuroreColors
+	}
+	// Phases are 16 bits angles; a full turn is 65536.
+	t := uint64(timeMS) * 65536 / uint64(period)
+	// Slow breathing of the whole aurora, in [32768, 65535].
+	breath := 49151 + sin16(uint16(t/3))/2
+	for i := range pixels {
+		x := uint64(i) * 65536 / uint64(scale)
+		// Two waves drifting at different speeds so the curtains slowly deform.
+		v := (sin16(uint16(x+t)) + sin16(uint16(x*2/3-t*5/7+0x5000))) / 2
+		if v <= 0 {
+			pixels[i] = Color{}
+			continue
+		}
+		// Only the crests are lit, squared for sharper edges.
+		v = v * v >> 15
+		level := v * breath >> 16 * 255 / 32767
+		pixels[i] = colors.At(uint16(sin16(uint16(x/4+t/2)) + 32767))
+		pixels[i].Dim(uint8(level))
+	}
+}
+
+// NightStars draws stars that twinkle smoothly.
+//
+// Each pixel is independent so the stars stay at the same place on strips of
+// any length.
+type NightStars struct {
+	Palette   Palette // Colors of the stars, each star picks one along it; defaults to white
+	Density   SValue  // Number of stars per 256 pixels, e.g. 64; 0 shows no star
+	TwinkleMS SValue  // Average twinkling period; 0 (default) is 2000
+	Seed      uint32  // Changes the placement of stars
+}
+
+// Render implements Pattern.
+func (n *NightStars) Render(pixels Frame, timeMS uint32) {
+	density := uint32(MinMax32(n.Density.Eval(timeMS, len(pixels)), 0, 256))
+	twinkle := uint64(MinMax32(n.TwinkleMS.Eval(timeMS, len(pixels)), 0, 1<<30))
+	if twinkle == 0 {
+		twinkle = 2000
+	} else if twinkle < 2 {
+		twinkle = 2
+	}
+	for i := range pixels {
+		pixels[i] = Color{}
+		h := hash32(n.Seed ^ hash32(uint32(i)))
+		if h&0xff >= density {
+			continue
+		}
+		// Use gamma == 2 and limit intensity at 50%.
+		b := int32(h>>8&0xff + 1)
+		base := b * b >> 9
+		// Each star has its own period within [50%, 150%] of TwinkleMS and its
+		// own phase.
+		p := twinkle/2 + uint64(h>>16&0xff)*twinkle/256
+		phase := uint16(uint64(timeMS)*65536/p + uint64(h>>24)<<8)
+		// Twinkle between 50% and 100% of the base intensity.
+		level := base * (98304 + sin16(phase)) >> 17
+		c := Color{0xFF, 0xFF, 0xFF}
+		if len(n.Palette.Stops) != 0 {
+			c = n.Palette.At(uint16(hash32(h)))
+		}
+		c.Dim(uint8(level))
+		pixels[i] = c
+	}
+}
+
+// Twinkle draws pixels that each fade in and out at their own pace.
+//
+// Each pixel has its own period and phase derived from Seed and its index.
+// At every cycle, it is randomly chosen to twinkle or not with a likelihood of
+// Density. It is a pure function of timeMS, so devices synchronized on the
+// clock sparkle identically.
+type Twinkle struct {
+	Child    SPattern // Colors of the twinkles; defaults to white
+	Density  SValue   // Number of twinkling pixels per 256 pixels, e.g. 64; 0 shows no twinkle
+	PeriodMS SValue   // Average duration of a twinkle; 0 (default) is 2000
+	Curve    Curve    // Fade in, then reversed for the fade out; defaults to EaseOut if not set
+	Seed     uint32   // Changes the placement of twinkles
+	buf      Frame
+}
+
+// Render implements Pattern.
+func (t *Twinkle) Render(pixels Frame, timeMS uint32) {
+	density := uint32(MinMax32(t.Density.Eval(timeMS, len(pixels)), 0, 256))
+	period := uint64(MinMax32(t.PeriodMS.Eval(timeMS, len(pixels)), 0, 1<<30))
+	if period == 0 {
+		period = 2000
+	} else if period < 2 {
+		period = 2
+	}
+	if t.Child.Pattern != nil {
+		t.buf.reset(len(pixels))
+		t.Child.Render(t.buf, timeMS)
+	}
+	for i := range pixels {
+		pixels[i] = Color{}
+		h := hash32(t.Seed ^ hash32(uint32(i)))
+		// Period within [50%, 150%] of PeriodMS and phase.
+		p := period/2 + uint64(h&0xff)*period/256
+		x := uint64(timeMS) + uint64(h>>8)*p>>24
+		cycle := uint32(x / p)
+		if hash32(h^cycle)&0xff >= density {
+			continue
+		}
+		// Fade in for the first half of the cycle, then out.
+		u := (x % p) * 131070 / p
+		if u > 65535 {
+			u = 131070 - u
+		}
+		intensity := t.Curve.Scale(uint16(u))
+		c := Color{0xFF, 0xFF, 0xFF}
+		if t.Child.Pattern != nil {
+			c = t.buf[i]
+		}
+		c.Dim(uint8(intensity >> 8))
+		pixels[i] = c
+	}
+}
+
+// CometHead is one comet of a Comet.
+type CometHead struct {
+	Color       Color
+	Position    SValue      // Position of the head in 1/256th of pixel, added to the movement
+	MovePerHour MovePerHour // Expressed in number of pixels per hour; the movement is sub-pixel
+	TailLength  SValue      // Length of the tail in pixels
+	Curve       Curve       // Decay of the tail, from the head to its end; defaults to EaseOut if not set
+	Reverse     bool        // Mirrors the comet so it moves from the end toward the beginning
+	Bounce      bool        // Bounces at the ends of the strip instead of wrapping around
+}
+
+// Comet draws comets with an anti-aliased head and a decaying tail.
+//
+// Overlapping comets add up.
+type Comet struct {
+	Heads []CometHead
+}
+
+// Render implements Pattern.
+func (c *Comet) Render(pixels Frame, timeMS uint32) {
+	for i := range pixels {
+		pixels[i] = Color{}
+	}
+	for i := range c.Heads {
+		c.Heads[i].draw(pixels, timeMS)
+	}
+}
+
+func (h *CometHead) draw(pixels Frame, timeMS uint32) {
+	l := len(pixels)
+	if l == 0 {
+		return
+	}
+	// All the positions are in 1/256th of pixel.
+	pos := h.MovePerHour.Eval256(timeMS, l, 0) + int(h.Position.Eval(timeMS, l))
+	dir := 1
+	wrap := 0
+	if h.Bounce {
+		if l == 1 {
+			pos = 0
+		} else {
+			// Bounce over the strip back and forth.
+			cycle := 2 * (l - 1) * 256
+			pos %= cycle
+			if pos < 0 {
+				pos += cycle
+			}
+			if pos > cycle/2 {
+				pos = cycle - pos
+				dir = -dir
+			}
+		}
+	} else {
+		wrap = l * 256
+		pos %= wrap
+		if pos < 0 {
+			pos += wrap
+		}
+	}
+	if h.Reverse {
+		// Mirror the whole movement.
+		pos = (l-1)*256 - pos
+		dir = -dir
+	}
+	tail := 256
+	if t := int(h.TailLength.Eval(timeMS, l)) * 256; t > tail {
+		tail = t
+	}
+	for i := range pixels {
+		// Distance from the head toward the tail.
+		d := (pos - i*256) * dir
+		if wrap != 0 {
+			// The tail wraps around; keep the distance in [-256, wrap-256).
+			d = (d+256)%wrap - 256
+			if d < -256 {
+				d += wrap
+			}
+		}
+		var level int
+		switch {
+		case d <= -256 || d >= tail:
+			continue
+		case d < 0:
+			// Antialias the head.
+			level = (256 + d) * 255 >> 8
+		default:
+			level = int(h.Curve.Scale(uint16(65535-d*65535/tail))) >> 8
+		}
+		col := h.Color
+		col.Dim(uint8(level))
+		pixels[i].Add(col)
+	}
+}
+
+// Fire draws flames rising from the base of the strip.
+//
+// It is stateless: the flames are layered value noise scrolling away from the
+// base, cooled down by the distance from the base and colored with a heat
+// palette.
+type Fire struct {
+	Cooling  SValue  // How fast the flames cool down while rising, [1, 255]; 0 (default) is 55
+	Sparking SValue  // Likelihood of new sparks at the base, [1, 255]; 0 (default) is 120
+	Palette  Palette // Colors from cold to hot; defaults to black, red, orange and white
+	Reverse  bool    // Anchors the base at the end of the strip instead of the beginning
+	Seed     uint32  // Changes the shape of the flames
+}
+
+var defaultFirePalette = Palette{Stops: []PaletteStop{{Color{}, 0}, {Color{0xFF, 0x00, 0x00}, 21845}, {Color{0xFF, 0xA0, 0x00}, 43690}, {Color{0xFF, 0xFF, 0xC0}, 65535}}}
+
+// Render implements Pattern.
+func (f *Fire) Render(pixels Frame, timeMS uint32) {
+	l := len(pixels)
+	cooling := MinMax32(f.Cooling.Eval(timeMS, l), 0, 255)
+	if cooling == 0 {
+		cooling = 55
+	}
+	sparking := uint32(MinMax32(f.Sparking.Eval(timeMS, l), 0, 255))
+	if sparking == 0 {
+		sparking = 120
+	}
+	palette := &f.Palette
+	if len(palette.Stops) == 0 {
+		palette = &defaultFirePalette
+	}
+	// The noise lattice cells are 4 pixels wide and 100ms long. The flames rise
+	// at 32 pixels per second. Start far from 0 to not wrap for a long time.
+	rise := 1<<31 - uint32(uint64(timeMS)*2048/1000)
+	y := uint32(uint64(timeMS) * 256 / 100)
+	sparks := hash32(f.Seed ^ hash32(timeMS/60))
+	for i := range pixels {
+		d := i
+		if f.Reverse {
+			d = l - 1 - i
+		}
+		x := rise + uint32(d)*64
+		heat := (2*valueNoise(f.Seed, x, y) + valueNoise(f.Seed+1, 2*x, 2*y)) / 3
+		if d < 3 && (sparks>>(8*uint(d)))&0xff < sparking {
+			heat += int32(96 * (3 - d) / 3)
+		}
+		heat = MinMax32(heat*(256-int32(d)*cooling/8)>>8, 0, 255)
+		pixels[i] = palette.At8(uint8(heat))
+	}
+}
+
+// Noise draws smooth noise that slowly evolves over time.
+//
+// It is useful for organic effects like water or clouds. The noise is mapped
+// through Palette: a Palette is sampled directly, a Frame is stretched evenly
+// from its first to its last color, any other pattern is rendered on 256
+// pixels, one per noise level. It defaults to grayscale.
+type Noise struct {
+	Kind     NoiseKind
+	Scale    SValue   // Size of a noise feature in pixels; 0 (default) is 16
+	PeriodMS SValue   // Time for the noise to evolve by one feature; 0 (default) is 1000
+	Octaves  int      // Number of layers of finer details, [1, 8]
+	Seed     uint32   // Changes the noise
+	Palette  SPattern // Maps noise levels to colors
+	buf      Frame
+}
+
+// Render implements Pattern.
+func (n *Noise) Render(pixels Frame, timeMS uint32) {
+	scale := MinMax32(n.Scale.Eval(timeMS, len(pixels)), 0, 65535)
+	if scale == 0 {
+		scale = 16
+	}
+	period := MinMax32(n.PeriodMS.Eval(timeMS, len(pixels)), 0, 1<<30)
+	if period == 0 {
+		period = 1000
+	}
+	octaves := MinMax(n.Octaves, 1, 8)
+	p, isPalette := n.Palette.Pattern.(*Palette)
+	if f, ok := n.Palette.Pattern.(Frame); ok {
+		n.buf.reset(256)
+		if len(f) != 0 {
+			for v := range n.buf {
+				pos := v * (len(f) - 1) * 256 / 255
+				c := f[pos>>8]
+				if k := pos>>8 + 1; k < len(f) {
+					c.Mix(f[k], uint8(pos))
+				}
+				n.buf[v] = c
+			}
+		}
+	} else if !isPalette && n.Palette.Pattern != nil {
+		n.buf.reset(256)
+		n.Palette.Render(n.buf, timeMS)
+	}
+	y := uint32(uint64(timeMS) * 256 / uint64(period))
+	for i := range pixels {
+		x := uint32(uint64(i) * 256 / uint64(scale))
+		// Each octave has twice the details and half the weight of the previous.
+		var sum, total int32
+		for o := 0; o < octaves; o++ {
+			w := int32(1) << uint(octaves-1-o)
+			sum += w * n.Kind.Eval(n.Seed+uint32(o), x<<uint(o), y<<uint(o))
+			total += w
+		}
+		v := sum / total
+		switch {
+		case isPalette:
+			pixels[i] = p.At8(uint8(v))
+		case n.Palette.Pattern != nil:
+			pixels[i] = n.buf[v]
+		default:
+			pixels[i] = Color{uint8(v), uint8(v), uint8(v)}
+		}
+	}
+}
+
+// Lightning draws a single lightning strike.
+type Lightning struct {
+	Center    SValue // offset of the center, from the left
+	HalfWidth SValue // in pixels
+	Intensity int    // the maximum intensity; 0 means 255
+	StartMS   SValue // when it started
+}
+
+var lightningCycle = []struct {
+	offsetMS  uint32
+	intensity uint8
+}{
+	{0, 0},
+	{150, 255},
+	{300, 0},
+	{450, 255},
+	{600, 0},
+	{750, 255},
+	{900, 0},
+	{1050, 76},
+	{1200, 51},
+	{1350, 26},
+	{1500, 0},
+}
+
+// lightningMS is the duration of a lightning strike.
+const lightningMS = 1500
+
+// Render implements Pattern.
+func (l *Lightning) Render(pixels Frame, timeMS uint32) {
+	for i := range pixels {
+		pixels[i] = Color{}
+	}
+	// Will fail after 25 days.
+	offset := timeMS - uint32(l.StartMS.Eval(timeMS, len(pixels)))
+	max := uint8(255)
+	if l.Intensity > 0 && l.Intensity < 255 {
+		max = uint8(l.Intensity)
+	}
+	center := l.Center.Eval(timeMS, len(pixels))
+	halfWidth := l.HalfWidth.Eval(timeMS, len(pixels))
+	drawLightning(pixels, offset, center, halfWidth, max)
+}
+
+// drawLightning adds the lightning strike at offset in its cycle to pixels.
+func drawLightning(pixels Frame, offset uint32, center, halfWidth int32, max uint8) {
+	intensity := uint8(0)
+	for i := 1; i < len(lightningCycle); i++ {
+		if lightningCycle[i].offsetMS > offset {
+			intensity = uint8(uint16(lightningCycle[i-1].intensity) * uint16(max) / 255)
+			break
+		}
+	}
+	if intensity == 0 || halfWidth <= 0 || len(pixels) == 0 {
+		return
+	}
+	left := center - halfWidth
+	right := center + halfWidth
+	width := right - left
+	min := MinMax32(left, 0, int32(len(pixels)-1))
+	max32 := MinMax32(right, 0, int32(len(pixels)-1))
+	b := Bell{}
+	for i := min; i <= max32; i++ {
+		x := (i - left) * 65535 / width
+		c := Color{intensity, intensity, intensity}
+		c.Dim(uint8(b.Scale(uint16(x)) >> 8))
+		pixels[i].Add(c)
+	}
+}
+
+// Thunderstorm creates strobe-like lightning strikes at random positions.
+//
+// It is deterministic: the strikes are derived from Seed and from the time
+// bucket of AvgMS they fall in, so any timeMS can be rendered without knowing
+// the previous ones. Overlapping strikes add up.
+type Thunderstorm struct {
+	AvgMS int    // Average between lightning strikes; at least 100
+	Seed  uint32 // Changes the sequence of strikes
+}
+
+// Render implements Pattern.
+func (t *Thunderstorm) Render(pixels Frame, timeMS uint32) {
+	for i := range pixels {
+		pixels[i] = Color{}
+	}
+	l := uint32(len(pixels))
+	if l == 0 {
+		return
+	}
+	avg := uint32(MinMax(t.AvgMS, 100, 1<<30))
+	// Each bucket of avg ms has exactly one strike at a random offset. Look at
+	// all the buckets that can have a strike still visible.
+	first := uint32(0)
+	if timeMS >= lightningMS {
+		first = (timeMS - lightningMS) / avg
+	}
+	for b := first; b <= timeMS/avg; b++ {
+		h := hash32(t.Seed ^ hash32(b))
+		start := b*avg + h%avg
+		if start > timeMS {
+			continue
+		}
+		h = hash32(h)
+		center := int32(h % l)
+		h = hash32(h)
+		halfWidth := int32(2 + (h>>8)%(l/4+1))
+		drawLightning(pixels, timeMS-start, center, halfWidth, uint8(128+h>>25))
+	}
+}
+
+// WishingStar draws a wishing star from time to time.
+//
+// It will only draw one star at a time. To increase the likelihood of getting
+// many simultaneously, create multiple instances with a different Seed.
+//
+// It is deterministic: each star is derived from its index, timeMS divided by
+// AverageDelayMS, so any timeMS can be rendered without knowing the previous
+// ones. The start offset, position, direction, speed, intensity and trail
+// length are random for each star.
+type WishingStar struct {
+	DurationMS     SValue // Duration of a star; it is capped to AverageDelayMS
+	AverageDelayMS SValue // Average delay between each wishing star
+	Seed           uint32 // Changes the sequence of stars
+}
+
+// Render implements Pattern.
+func (w *WishingStar) Render(pixels Frame, timeMS uint32) {
+	for i := range pixels {
+		pixels[i] = Color{}
+	}
+	l := int64(len(pixels))
+	dur := w.DurationMS.Eval(timeMS, len(pixels))
+	delay := w.AverageDelayMS.Eval(timeMS, len(pixels))
+	if l == 0 || dur <= 0 || delay <= 0 {
+		return
+	}
+	if dur > delay {
+		// Each star must be done before the next one can start.
+		dur = delay
+	}
+	// Create a deterministic replay by using the index of the wishing star as
+	// the seed. Always calculate things in the same order to keep the
+	// calculation deterministic.
+	index := timeMS / uint32(delay)
+	h := hash32(w.Seed ^ hash32(index))
+	offset := h % uint32(delay-dur+1)
+	elapsed := timeMS - index*uint32(delay)
+	if elapsed < offset || elapsed-offset >= uint32(dur) {
+		return
+	}
+	// Progress of the star in [0, 65535].
+	progress := int64(elapsed-offset) * 65536 / int64(dur)
+	h = hash32(h)
+	start := int64(h % uint32(l))
+	h = hash32(h)
+	dir := int64(1)
+	if h&1 != 0 {
+		dir = -1
+	}
+	// Travels between a quarter and the whole strip.
+	travel := l*64 + int64(h>>8)%(l*192+1)
+	h = hash32(h)
+	intensity := 128 + int64(h>>25)
+	h = hash32(h)
+	trail := 2*256 + int64(h>>8)%(l*64+1)
+
+	// Position of the head in 1/256th of pixel. It fades as it burns.
+	head := start*256 + dir*travel*progress>>16
+	intensity = intensity * (65536 - progress) >> 16
+	for i := range pixels {
+		// Distance from the head toward the tail, in 1/256th of pixel.
+		d := (head - int64(i)*256) * dir
+		var level int64
+		switch {
+		case d <= -256 || d >= trail:
+			continue
+		case d < 0:
+			// Antialias the head.
+			level = intensity * (256 + d) >> 8
+		default:
+			level = intensity * (trail - d) / trail
+		}
+		pixels[i] = Color{uint8(level), uint8(level), uint8(level)}
+	}
+}
+
 var _ image.Image = &Color{}
 var _ image.Image = Frame{}
@@ -57,6 +57,31 @@ func TestColor_Mix(t *testing.T) {
 	}
 }
 
+func TestColor_MixLinear(t *testing.T) {
+	white := Color{255, 255, 255}
+	black := Color{0, 0, 0}
+	data := []struct {
+		start    Color
+		new      Color
+		mix      uint8
+		expected Color
+	}{
+		{white, black, 0, white},
+		{white, black, 255, black},
+		{black, white, 128, Color{188, 188, 188}},
+		{Color{255, 0, 0}, Color{0, 255, 0}, 128, Color{187, 188, 0}},
+		{Color{0x10, 0x20, 0x30}, black, 0, Color{0x10, 0x20, 0x30}},
+		{black, Color{0x10, 0x20, 0x30}, 255, Color{0x10, 0x20, 0x30}},
+	}
+	for i, line := range data {
+		c := line.start
+		c.MixLinear(line.new, line.mix)
+		if c != line.expected {
+			t.Fatalf("%d: %v.MixLinear(%v, %v) = %v; expected %v", i, line.start, line.new, line.mix, c, line.expected)
+		}
+	}
+}
+
 func TestColor_FromString(t *testing.T) {
 	c := Color{}
 	if err := c.FromString("123456"); err == nil {
@@ -70,6 +95,321 @@ func TestColor_FromString(t *testing.T) {
 	}
 }
 
+func TestColor_FromString_hsv(t *testing.T) {
+	c := Color{}
+	if err := c.FromString("hsv(120,100%,100%)"); err != nil {
+		t.Fatal(err)
+	}
+	if c != (Color{0x00, 0xFF, 0x00}) {
+		t.Fatal(c)
+	}
+	if err := c.FromString("hsv(120,100%)"); err == nil {
+		t.Fail()
+	}
+}
+
+func TestColor_Luminance(t *testing.T) {
+	data := []struct {
+		c        Color
+		expected uint8
+	}{
+		{Color{}, 0},
+		{Color{0xFF, 0xFF, 0xFF}, 0xFF},
+		{Color{0xFF, 0x00, 0x00}, 54},
+		{Color{0x00, 0xFF, 0x00}, 182},
+		{Color{0x00, 0x00, 0xFF}, 19},
+		{Color{0x80, 0x80, 0x80}, 0x80},
+	}
+	for i, line := range data {
+		if l := line.c.Luminance(); l != line.expected {
+			t.Fatalf("%d: %v.Luminance() = %d, expected %d", i, line.c, l, line.expected)
+		}
+	}
+}
+
+func TestColor_HSV(t *testing.T) {
+	data := []struct {
+		c        Color
+		expected HSV
+	}{
+		{Color{}, HSV{}},
+		{Color{0x80, 0x80, 0x80}, HSV{0, 0, 0x80}},
+		{Color{0xFF, 0x00, 0x00}, HSV{0, 255, 255}},
+		{Color{0x00, 0xFF, 0x00}, HSV{21845, 255, 255}},
+		{Color{0x00, 0x00, 0xFF}, HSV{43690, 255, 255}},
+		{Color{0xFF, 0x00, 0x80}, HSV{60054, 255, 255}},
+		{Color{0x80, 0x40, 0x40}, HSV{0, 128, 128}},
+	}
+	for i, line := range data {
+		if h := line.c.HSV(); h != line.expected {
+			t.Fatalf("%d: %v.HSV() = %v, expected %v", i, line.c, h, line.expected)
+		}
+		if c := line.expected.ToRGB(); c != line.c {
+			t.Fatalf("%d: %v.ToRGB() = %v, expected %v", i, line.expected, c, line.c)
+		}
+	}
+}
+
+func TestPalette(t *testing.T) {
+	p := &Palette{}
+	if err := p.FromString("P#000000,#ff0000,#ffffff"); err != nil {
+		t.Fatal(err)
+	}
+	if p.Stops[1].Position != 32767 {
+		t.Fatal(p.Stops)
+	}
+	data := []struct {
+		pos      uint16
+		expected Color
+	}{
+		{0, Color{}},
+		{16384, Color{0x80, 0x00, 0x00}},
+		{32767, Color{0xFF, 0x00, 0x00}},
+		{65535, Color{0xFF, 0xFF, 0xFF}},
+	}
+	for i, line := range data {
+		if c := p.At(line.pos); c != line.expected {
+			t.Fatalf("%d: At(%d) = %s, expected %s", i, line.pos, &c, &line.expected)
+		}
+	}
+	if c := p.At8(128); c != (Color{0xFF, 0x01, 0x01}) {
+		t.Fatal(c)
+	}
+	testFrame(t, p, expectation{0, Frame{{}, {0xFF, 0, 0}, {0xFF, 0xFF, 0xFF}}})
+
+	if err := p.FromString("Pwrap:#ff0000,#0000ff 50%"); err != nil {
+		t.Fatal(err)
+	}
+	if c := p.At8(192); c != (Color{0x80, 0x00, 0x7F}) {
+		t.Fatal(c)
+	}
+	testFrame(t, p, expectation{0, Frame{{0xFF, 0, 0}, {0x7F, 0, 0x80}, {0, 0, 0xFF}, {0x80, 0, 0x7F}}})
+
+	if err := p.FromString("Phsv(0,100%,100%),hsv(120, 100%, 100%) 100%"); err != nil {
+		t.Fatal(err)
+	}
+	if p.Wrap || len(p.Stops) != 2 || p.Stops[1] != (PaletteStop{Color{0, 0xFF, 0}, 65535}) {
+		t.Fatal(p)
+	}
+	for _, bad := range []string{"", "Q#000000", "P#00000", "P#000000 50%,#ffffff 20%", "P#000000 101%", "P#000000 a%", "Px"} {
+		if err := p.FromString(bad); err == nil {
+			t.Fatal(bad)
+		}
+	}
+	// Positions survive a round trip.
+	for i := 0; i < 65536; i += 7 {
+		p := &Palette{Stops: []PaletteStop{{Color{}, 0}, {Color{}, uint16(i)}, {Color{}, 65535}}}
+		p2 := &Palette{}
+		if err := p2.FromString(p.String()); err != nil || p2.Stops[1] != p.Stops[1] {
+			t.Fatalf("%d: %s: %v", i, p, err)
+		}
+	}
+}
+
+func TestHSV(t *testing.T) {
+	p := &HSV{}
+	if err := p.FromString("hsv(240, 100%, 50%)"); err != nil {
+		t.Fatal(err)
+	}
+	testFrame(t, p, expectation{0, Frame{{0, 0, 0x7F}, {0, 0, 0x7F}}})
+	if s := p.String(); s != "hsv(240,100%,50%)" {
+		t.Fatal(s)
+	}
+	for _, bad := range []string{"hsv(1,2)", "hsv(a,2,3)", "hsv(1,101%,3)", "hsv(-1,1,1)", "hsl(1,2,3)"} {
+		if err := p.FromString(bad); err == nil {
+			t.Fatal(bad)
+		}
+	}
+}
+
+func TestHueCycle(t *testing.T) {
+	p := &HueCycle{Saturation: SValue{Const(255)}, Value: SValue{Const(255)}, Span: SValue{Const(360)}}
+	e := []expectation{
+		{0, Frame{{0xFF, 0, 0}, {0, 0xFF, 0}, {0, 0, 0xFF}}},
+		{0, Frame{{0xFF, 0, 0}, {0, 0xFF, 0xFF}}},
+	}
+	testFrames(t, p, e)
+	p = &HueCycle{Saturation: SValue{Const(255)}, Value: SValue{Const(255)}, MovePerHour: MovePerHour{Const(1296000)}}
+	e = []expectation{
+		{0, Frame{{0xFF, 0, 0}}},
+		{500, Frame{{0, 0xFF, 0xFF}}},
+		{1000, Frame{{0xFF, 0, 0}}},
+	}
+	testFrames(t, p, e)
+}
+
+func TestAurore(t *testing.T) {
+	p := &Aurore{}
+	e := []expectation{
+		{0, Frame{{0x00, 0x14, 0x27}, {0x01, 0x19, 0x34}, {0x03, 0x1d, 0x41}, {0x05, 0x20, 0x4b}, {0x08, 0x21, 0x51}, {0x0a, 0x1f, 0x53}, {0x0b, 0x1c, 0x50}, {0x0c, 0x18, 0x48}, {0x0b, 0x12, 0x3c}, {0x09, 0x0c, 0x2c}, {0x07, 0x08, 0x1d}, {0x03, 0x03, 0x0e}}},
+		{5000, Frame{{0x3c, 0x18, 0xa8}, {0x43, 0x17, 0xb5}, {0x47, 0x15, 0xb7}, {0x46, 0x11, 0xae}, {0x41, 0x0d, 0x9d}, {0x39, 0x09, 0x85}, {0x2e, 0x06, 0x69}, {0x23, 0x03, 0x4c}, {0x17, 0x02, 0x31}, {0x0c, 0x00, 0x1a}, {0x05, 0x00, 0x0a}, {0x00, 0x00, 0x01}}},
+		{10000, Frame{{}, {}, {}, {}, {}, {}, {}, {}, {}, {}, {}, {}}},
+		{25000, Frame{{0x00, 0x68, 0x38}, {0x00, 0x57, 0x2c}, {0x00, 0x42, 0x1f}, {0x00, 0x2c, 0x13}, {0x00, 0x17, 0x09}, {0x00, 0x08, 0x03}, {}, {}, {}, {}, {}, {}}},
+	}
+	testFrames(t, p, e)
+	// The output doesn't depend on the strip length.
+	testFrame(t, p, expectation{5000, e[1].colors[:5]})
+}
+
+func TestNightStars(t *testing.T) {
+	p := &NightStars{Density: SValue{Const(128)}, Palette: Palette{Stops: []PaletteStop{{Color{0xFF, 0, 0}, 0}, {Color{0, 0, 0xFF}, 65535}}}, TwinkleMS: SValue{Const(1000)}}
+	e := []expectation{
+		{0, Frame{{}, {0x03, 0x00, 0x05}, {}, {0x0f, 0x00, 0x5d}, {0x01, 0x00, 0x0b}, {}, {0x48, 0x00, 0x07}, {}}},
+		{250, Frame{{}, {0x02, 0x00, 0x04}, {}, {0x09, 0x00, 0x39}, {0x00, 0x00, 0x09}, {}, {0x5a, 0x00, 0x09}, {}}},
+	}
+	testFrames(t, p, e)
+	// The output doesn't depend on the strip length.
+	testFrame(t, p, expectation{250, e[1].colors[:4]})
+	p.Density = SValue{Const(0)}
+	testFrame(t, p, expectation{250, Frame{{}, {}, {}, {}, {}, {}, {}, {}}})
+}
+
+func TestTwinkle(t *testing.T) {
+	p := &Twinkle{Density: SValue{Const(128)}, PeriodMS: SValue{Const(1000)}}
+	e := []expectation{
+		{0, Frame{{}, {0xdc, 0xdc, 0xdc}, {0x98, 0x98, 0x98}, {0xef, 0xef, 0xef}, {0xf8, 0xf8, 0xf8}, {0x7d, 0x7d, 0x7d}, {0x13, 0x13, 0x13}, {0xd5, 0xd5, 0xd5}}},
+		{500, Frame{{}, {0x63, 0x63, 0x63}, {0xf4, 0xf4, 0xf4}, {}, {0xfd, 0xfd, 0xfd}, {0xa3, 0xa3, 0xa3}, {0xe9, 0xe9, 0xe9}, {0x7e, 0x7e, 0x7e}}},
+	}
+	testFrames(t, p, e)
+	// The output doesn't depend on the strip length.
+	testFrame(t, p, expectation{500, e[1].colors[:3]})
+	p.Child = SPattern{&Color{0xFF, 0, 0}}
+	p.Curve = Direct
+	testFrame(t, p, expectation{500, Frame{{}, {0x41, 0x00, 0x00}, {0xd6, 0x00, 0x00}, {}, {0xee, 0x00, 0x00}, {0x74, 0x00, 0x00}, {0xc2, 0x00, 0x00}, {0x56, 0x00, 0x00}}})
+	p.Density = SValue{Const(0)}
+	testFrame(t, p, expectation{500, Frame{{}, {}, {}, {}}})
+}
+
+func TestComet(t *testing.T) {
+	w := Color{0xFF, 0xFF, 0xFF}
+	p := &Comet{Heads: []CometHead{{Color: w, MovePerHour: MovePerHour{Const(3600 * 4)}, TailLength: SValue{Const(3)}, Curve: Direct}}}
+	e := []expectation{
+		{0, Frame{{0xfe, 0xfe, 0xfe}, {}, {}, {}, {}, {}, {0x54, 0x54, 0x54}, {0xa9, 0xa9, 0xa9}}},
+		{125, Frame{{0xd4, 0xd4, 0xd4}, {0x7e, 0x7e, 0x7e}, {}, {}, {}, {}, {0x29, 0x29, 0x29}, {0x7f, 0x7f, 0x7f}}},
+		{1875, Frame{{0x7e, 0x7e, 0x7e}, {}, {}, {}, {}, {0x29, 0x29, 0x29}, {0x7f, 0x7f, 0x7f}, {0xd4, 0xd4, 0xd4}}},
+	}
+	testFrames(t, p, e)
+	p.Heads[0].Bounce = true
+	e = []expectation{
+		{1875, Frame{{}, {}, {}, {}, {}, {}, {0x7e, 0x7e, 0x7e}, {0xd4, 0xd4, 0xd4}}},
+		{2000, Frame{{}, {}, {}, {}, {}, {}, {0xfe, 0xfe, 0xfe}, {0xa9, 0xa9, 0xa9}}},
+	}
+	testFrames(t, p, e)
+	p.Heads[0].Bounce = false
+	p.Heads[0].Reverse = true
+	testFrame(t, p, expectation{125, Frame{{0x7f, 0x7f, 0x7f}, {0x29, 0x29, 0x29}, {}, {}, {}, {}, {0x7e, 0x7e, 0x7e}, {0xd4, 0xd4, 0xd4}}})
+	p.Heads = []CometHead{
+		{Color: Color{0xFF, 0, 0}, Position: SValue{Const(256)}},
+		{Color: Color{0, 0, 0xFF}, Position: SValue{Const(512)}, Reverse: true},
+	}
+	testFrame(t, p, expectation{0, Frame{{}, {0xFE, 0, 0}, {}, {}, {}, {0, 0, 0xFE}, {}, {}}})
+	// The position is sub-pixel.
+	p.Heads = []CometHead{{Color: w, Position: SValue{Const(128)}, Curve: Direct}}
+	testFrame(t, p, expectation{0, Frame{{0x7f, 0x7f, 0x7f}, {0x7e, 0x7e, 0x7e}, {}, {}}})
+}
+
+func TestFire(t *testing.T) {
+	p := &Fire{}
+	e := []expectation{
+		{0, Frame{{0xff, 0x9e, 0x00}, {0xff, 0x7c, 0x00}, {0xff, 0x59, 0x00}, {0xd2, 0x00, 0x00}, {0x78, 0x00, 0x00}, {0xf0, 0x00, 0x00}, {0xff, 0x60, 0x00}, {0xff, 0x7a, 0x00}}},
+		{500, Frame{{0xc6, 0x00, 0x00}, {0xc9, 0x00, 0x00}, {0xd8, 0x00, 0x00}, {0xe1, 0x00, 0x00}, {0xe7, 0x00, 0x00}, {0xe4, 0x00, 0x00}, {0xcc, 0x00, 0x00}, {0x75, 0x00, 0x00}}},
+	}
+	testFrames(t, p, e)
+	p.Cooling, p.Sparking = SValue{Const(55)}, SValue{Const(120)}
+	testFrames(t, p, e)
+	p.Reverse = true
+	r := make(Frame, len(e[1].colors))
+	for i, c := range e[1].colors {
+		r[len(r)-1-i] = c
+	}
+	testFrame(t, p, expectation{500, r})
+	p.Cooling = SValue{Const(255)}
+	pixels := make(Frame, 20)
+	p.Render(pixels, 500)
+	if !pixels[:10].isEqual(make(Frame, 10)) {
+		t.Fatal(pixels)
+	}
+}
+
+func TestNoise(t *testing.T) {
+	p := &Noise{Scale: SValue{Const(2)}}
+	e := []expectation{
+		{0, Frame{{}, {0x2c, 0x2c, 0x2c}, {0x58, 0x58, 0x58}, {0x47, 0x47, 0x47}, {0x36, 0x36, 0x36}, {0x4e, 0x4e, 0x4e}}},
+		{500, Frame{{0x12, 0x12, 0x12}, {0x2a, 0x2a, 0x2a}, {0x43, 0x43, 0x43}, {0x48, 0x48, 0x48}, {0x4d, 0x4d, 0x4d}, {0x64, 0x64, 0x64}}},
+	}
+	testFrames(t, p, e)
+	p.Kind = NoisePerlin
+	testFrame(t, p, expectation{500, Frame{{0x80, 0x80, 0x80}, {0x60, 0x60, 0x60}, {0x40, 0x40, 0x40}, {0xa0, 0xa0, 0xa0}, {0xc0, 0xc0, 0xc0}, {0x70, 0x70, 0x70}}})
+	p.Kind = NoiseSimplex
+	testFrame(t, p, expectation{500, Frame{{0x3f, 0x3f, 0x3f}, {0x80, 0x80, 0x80}, {0x72, 0x72, 0x72}, {0x10, 0x10, 0x10}, {0x49, 0x49, 0x49}, {0x5d, 0x5d, 0x5d}}})
+	p.Kind = NoiseValue
+	p.Octaves = 3
+	testFrame(t, p, expectation{500, Frame{{0x62, 0x62, 0x62}, {0x5d, 0x5d, 0x5d}, {0x34, 0x34, 0x34}, {0x51, 0x51, 0x51}, {0x7f, 0x7f, 0x7f}, {0x7c, 0x7c, 0x7c}}})
+	p.Octaves = 0
+	p.Palette = SPattern{&Gradient{Left: SPattern{&Color{}}, Right: SPattern{&Color{0, 0, 0xFF}}}}
+	testFrame(t, p, expectation{500, Frame{{0x00, 0x00, 0x1d}, {0x00, 0x00, 0x41}, {0x00, 0x00, 0x65}, {0x00, 0x00, 0x6b}, {0x00, 0x00, 0x72}, {0x00, 0x00, 0x8f}}})
+	p.Palette = SPattern{&Palette{Stops: []PaletteStop{{Color{}, 0}, {Color{0xFF, 0, 0}, 65535}}}}
+	r := make(Frame, len(e[1].colors))
+	for i, c := range e[1].colors {
+		r[i] = Color{c.R, 0, 0}
+	}
+	testFrame(t, p, expectation{500, r})
+	// A Frame is stretched across the noise levels.
+	p.Palette = SPattern{Frame{{}, {0xFF, 0, 0}}}
+	testFrame(t, p, expectation{500, r})
+}
+
+func TestLightning(t *testing.T) {
+	p := &Lightning{Center: SValue{Const(5)}, HalfWidth: SValue{Const(2)}}
+	e := []expectation{
+		{0, Frame{{}, {}, {}, {}, {}, {}, {}, {}}},
+		{200, Frame{{}, {}, {}, {}, {0x7e, 0x7e, 0x7e}, {0xfe, 0xfe, 0xfe}, {0x7f, 0x7f, 0x7f}, {}}},
+		{1100, Frame{{}, {}, {}, {}, {0x25, 0x25, 0x25}, {0x4c, 0x4c, 0x4c}, {0x26, 0x26, 0x26}, {}}},
+		{1500, Frame{{}, {}, {}, {}, {}, {}, {}, {}}},
+	}
+	testFrames(t, p, e)
+}
+
+func TestThunderstorm(t *testing.T) {
+	p := &Thunderstorm{AvgMS: 1000}
+	e := []expectation{
+		{2000, Frame{{0x52, 0x52, 0x52}, {0xa5, 0xa5, 0xa5}, {0x53, 0x53, 0x53}, {}, {}, {}, {}, {}, {}, {}}},
+		{2500, Frame{{0x2b, 0x2b, 0x2b}, {0x84, 0x84, 0x84}, {0x9f, 0x9f, 0x9f}, {0x74, 0x74, 0x74}, {0x23, 0x23, 0x23}, {}, {}, {}, {}, {}}},
+		{3000, Frame{{}, {}, {}, {}, {}, {}, {}, {}, {}, {}}},
+	}
+	testFrames(t, p, e)
+	// Rendering is independent of the previous frames.
+	testFrame(t, &Thunderstorm{AvgMS: 1000}, e[1])
+	p.Seed = 1
+	pixels := make(Frame, 10)
+	p.Render(pixels, 2500)
+	if pixels.isEqual(e[1].colors) {
+		t.Fatal("Seed is ignored")
+	}
+}
+
+func TestWishingStar(t *testing.T) {
+	p := &WishingStar{DurationMS: SValue{Const(1000)}, AverageDelayMS: SValue{Const(5000)}}
+	e := []expectation{
+		{6250, Frame{{0x7c, 0x7c, 0x7c}, {0x76, 0x76, 0x76}, {0x4b, 0x4b, 0x4b}, {0x20, 0x20, 0x20}, {}, {}, {}, {}, {}, {}}},
+		{0, Frame{{0x80, 0x80, 0x80}, {}, {}, {}, {}, {}, {}, {}, {}, {}}},
+		{250, Frame{{0x42, 0x42, 0x42}, {0x3c, 0x3c, 0x3c}, {}, {}, {}, {}, {}, {}, {}, {}}},
+		{500, Frame{{0x18, 0x18, 0x18}, {0x38, 0x38, 0x38}, {0x10, 0x10, 0x10}, {}, {}, {}, {}, {}, {}, {}}},
+		{1000, Frame{{}, {}, {}, {}, {}, {}, {}, {}, {}, {}}},
+	}
+	testFrames(t, p, e)
+	p.DurationMS = SValue{}
+	testFrame(t, p, expectation{250, Frame{{}, {}}})
+	// The duration is capped to the delay so the star is done before the next.
+	p = &WishingStar{DurationMS: SValue{Const(2000)}, AverageDelayMS: SValue{Const(1000)}}
+	e = []expectation{
+		{500, Frame{{0x28, 0x28, 0x28}, {0x30, 0x30, 0x30}, {}, {}, {}, {}}},
+		{999, Frame{{}, {}, {}, {}, {}, {}}},
+		{1000, Frame{{}, {0xb3, 0xb3, 0xb3}, {0x7c, 0x7c, 0x7c}, {0x46, 0x46, 0x46}, {0x10, 0x10, 0x10}, {}}},
+	}
+	testFrames(t, p, e)
+}
+
 func TestColor_FromRGBString(t *testing.T) {
 	c := Color{}
 	if err := c.FromRGBString("12345"); err == nil {
@@ -111,6 +451,23 @@ func TestFrame_ToRGB(t *testing.T) {
 	}
 }
 
+func TestGamma(t *testing.T) {
+	f := Frame{{0, 128, 255}, {64, 64, 64}}
+	MakeGamma(1).Apply(f)
+	if !f.isEqual(Frame{{0, 128, 255}, {64, 64, 64}}) {
+		t.Fatal(f)
+	}
+	MakeGamma(2).Apply(f)
+	if !f.isEqual(Frame{{0, 64, 255}, {16, 16, 16}}) {
+		t.Fatal(f)
+	}
+	f = Frame{{128, 128, 128}}
+	MakeGammaRGB(1, 2, 3).Apply(f)
+	if !f.isEqual(Frame{{128, 64, 32}}) {
+		t.Fatal(f)
+	}
+}
+
 func TestFrame_isEqual(t *testing.T) {
 	f1 := Frame{{0x12, 0x34, 0x56}}
 	f2 := Frame{{0x12, 0x34, 0x56}, {}}
@@ -17,9 +17,10 @@ import (
 
 // ThumbnailsCache is a cache of animated GIF thumbnails for each pattern.
 type ThumbnailsCache struct {
-	NumberLEDs       int // Must be set before calling Thumbnail().
-	ThumbnailHz      int // Must be set before calling Thumbnail().
-	ThumbnailSeconds int // Must be set before calling Thumbnail().
+	NumberLEDs       int    // Must be set before calling Thumbnail().
+	ThumbnailHz      int    // Must be set before calling Thumbnail().
+	ThumbnailSeconds int    // Must be set before calling Thumbnail().
+	Gamma            *Gamma // Optional, set it to the same as the hardware so the previews match.
 
 	lock  sync.Mutex
 	c     chan struct{}     // Limits the number of concurrent GIF animation to number of CPU core.
@@ -78,6 +79,9 @@ func (t *ThumbnailsCache) GIF(serialized []byte) ([]byte, error) {
 	for frame := 0; frame < nbImg; frame++ {
 		since := uint32(1000 * frame / t.ThumbnailHz)
 		pat.Render(pixels[frame&1], since)
+		if t.Gamma != nil {
+			t.Gamma.Apply(pixels[frame&1])
+		}
 		if frame > 0 && pixels[0].isEqual(pixels[1]) {
 			// Skip a frame completely if its pixels didn't change at all from the
 			// previous frame.
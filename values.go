@@ -7,7 +7,12 @@
 package anim1d
 
 import (
+	"fmt"
+	"math"
 	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/maruel/fastbezier"
 )
@@ -104,6 +109,294 @@ func (r *Rand) Eval(timeMS uint32, l int) int32 {
 	return int32(rand.NewSource(int64(timeMS / m)).Int63())
 }
 
+// Wave is the shape of an Oscillator.
+type Wave string
+
+// All the kinds of waves.
+const (
+	Sine     Wave = "sin"    // Smooth wave, default value.
+	Triangle Wave = "tri"    // Linear rise then linear fall.
+	Sawtooth Wave = "saw"    // Linear rise then drop.
+	Square   Wave = "square" // Min for the first half of the period then Max.
+)
+
+// Oscillator is a value that cycles between Min and Max, also known as a LFO
+// (low frequency oscillator).
+//
+// All the waves start at Min at the beginning of the period. Sine and
+// Triangle reach Max at half the period.
+//
+// It serializes as a compact string "sin(period,min,max,phase)" where the
+// trailing arguments are optional and default to 0, 255 and 0. For example
+// "sin(2000)" is a breathing intensity with a period of 2 seconds.
+type Oscillator struct {
+	Wave     Wave
+	PeriodMS uint32 // Duration of a full cycle
+	PhaseMS  uint32 // Offset added to timeMS
+	Min      int32  // Value at the start of the period
+	Max      int32  // Value at the peak
+}
+
+// Eval implements Value.
+func (o *Oscillator) Eval(timeMS uint32, l int) int32 {
+	if o.PeriodMS == 0 {
+		return o.Min
+	}
+	a := uint16(uint64((timeMS+o.PhaseMS)%o.PeriodMS) * 65536 / uint64(o.PeriodMS))
+	var u uint16
+	switch o.Wave {
+	case Sawtooth:
+		u = a
+	case Square:
+		if a >= 0x8000 {
+			u = 65535
+		}
+	case Triangle:
+		u = uint16(32767 - tri16(a+0x4000))
+		// Stretch [0, 65534] to [0, 65535].
+		u += u >> 15
+	default:
+		u = uint16(32767 - sin16(a+0x4000))
+		u += u >> 15
+	}
+	return lerp16(o.Min, o.Max, int32(u))
+}
+
+// Keyframe is one point of a Keyframes value.
+type Keyframe struct {
+	TimeMS uint32 // Time of this keyframe
+	Value  int32  // Value at this keyframe
+	Curve  Curve  // Curve of the segment up to the next keyframe, defaults to EaseOut if not set; may overshoot
+}
+
+// KeyframesMode defines what a Keyframes does after its last keyframe.
+type KeyframesMode string
+
+// All the kinds of keyframes modes.
+const (
+	KeyframesHold     KeyframesMode = "hold"     // Keeps the last value, default value.
+	KeyframesLoop     KeyframesMode = "loop"     // Restarts from the first keyframe.
+	KeyframesPingPong KeyframesMode = "pingpong" // Plays the keyframes backward then forward again.
+)
+
+// Keyframes is a value interpolated between keyframes.
+//
+// Frames must be ordered by TimeMS. Before the first keyframe, the value is
+// the first keyframe's value. The looping modes cycle between the first and
+// last keyframes.
+type Keyframes struct {
+	Frames []Keyframe
+	Mode   KeyframesMode
+}
+
+// Eval implements Value.
+func (k *Keyframes) Eval(timeMS uint32, l int) int32 {
+	n := len(k.Frames)
+	if n == 0 {
+		return 0
+	}
+	first := k.Frames[0].TimeMS
+	last := k.Frames[n-1].TimeMS
+	if timeMS <= first {
+		return k.Frames[0].Value
+	}
+	if timeMS >= last {
+		d := uint64(last - first)
+		if d == 0 {
+			return k.Frames[n-1].Value
+		}
+		o := uint64(timeMS - first)
+		switch k.Mode {
+		case KeyframesLoop:
+			o %= d
+		case KeyframesPingPong:
+			if o %= 2 * d; o > d {
+				o = 2*d - o
+			}
+		default:
+			return k.Frames[n-1].Value
+		}
+		timeMS = first + uint32(o)
+	}
+	for i := 1; i < n; i++ {
+		b := &k.Frames[i]
+		if timeMS >= b.TimeMS {
+			continue
+		}
+		a := &k.Frames[i-1]
+		if timeMS <= a.TimeMS {
+			return a.Value
+		}
+		p := uint16(uint64(timeMS-a.TimeMS) * 65535 / uint64(b.TimeMS-a.TimeMS))
+		return lerp16(a.Value, b.Value, a.Curve.ScaleSigned(p))
+	}
+	return k.Frames[n-1].Value
+}
+
+// lerp16 returns a + (b-a)*u/65535 rounded to the nearest integer.
+//
+// u is normally in [0, 65535] but may overshoot.
+func lerp16(a, b, u int32) int32 {
+	d := (int64(b) - int64(a)) * int64(u)
+	if d >= 0 {
+		d += 32767
+	} else {
+		d -= 32767
+	}
+	return int32(int64(a) + d/65535)
+}
+
+// hash32 is a cheap integer hash. It is used to derive pseudo-random values
+// that only depend on their input, which keeps patterns stateless.
+func hash32(x uint32) uint32 {
+	x ^= x >> 16
+	x *= 0x7feb352d
+	x ^= x >> 15
+	x *= 0x846ca68b
+	x ^= x >> 16
+	return x
+}
+
+// valueNoise returns smooth 2D value noise in [0, 255].
+//
+// x and y are in 1/256th of a lattice cell. The value at each lattice point is
+// derived from seed and its coordinates, and is smoothly interpolated in
+// between.
+func valueNoise(seed, x, y uint32) int32 {
+	xi, yi := x>>8, y>>8
+	fx, fy := smooth8(x&0xff), smooth8(y&0xff)
+	v00 := latticeValue(seed, xi, yi)
+	v10 := latticeValue(seed, xi+1, yi)
+	v01 := latticeValue(seed, xi, yi+1)
+	v11 := latticeValue(seed, xi+1, yi+1)
+	a := v00 + (v10-v00)*fx>>8
+	b := v01 + (v11-v01)*fx>>8
+	return a + (b-a)*fy>>8
+}
+
+// latticeValue returns the pseudo-random value in [0, 255] of a lattice point.
+func latticeValue(seed, x, y uint32) int32 {
+	return int32(hash32(seed^hash32(x^hash32(y))) >> 24)
+}
+
+// smooth8 applies the smoothstep curve 3f²-2f³ to f in [0, 255].
+func smooth8(f uint32) int32 {
+	return int32(f * f * (768 - 2*f) >> 16)
+}
+
+// noiseGrad are the gradients used by perlinNoise and simplexNoise.
+var noiseGrad = [8][2]int64{{1, 1}, {-1, 1}, {1, -1}, {-1, -1}, {1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+
+// latticeGrad returns the pseudo-random gradient of a lattice point.
+func latticeGrad(seed, x, y uint32) [2]int64 {
+	return noiseGrad[hash32(seed^hash32(x^hash32(y)))>>29]
+}
+
+// perlinNoise returns smooth 2D gradient noise in [0, 255].
+//
+// x and y are in 1/256th of a lattice cell.
+func perlinNoise(seed, x, y uint32) int32 {
+	xi, yi := x>>8, y>>8
+	x0, y0 := int64(x&0xff), int64(y&0xff)
+	dot := func(dx, dy uint32, px, py int64) int64 {
+		g := latticeGrad(seed, xi+dx, yi+dy)
+		return g[0]*px + g[1]*py
+	}
+	fx, fy := int64(smooth8(x&0xff)), int64(smooth8(y&0xff))
+	v00 := dot(0, 0, x0, y0)
+	v10 := dot(1, 0, x0-256, y0)
+	v01 := dot(0, 1, x0, y0-256)
+	v11 := dot(1, 1, x0-256, y0-256)
+	a := v00 + (v10-v00)*fx>>8
+	b := v01 + (v11-v01)*fx>>8
+	// The result is within about ±256.
+	return int32(MinMax(int(128+(a+(b-a)*fy>>8)/2), 0, 255))
+}
+
+// simplexNoise returns smooth 2D simplex noise in [0, 255].
+//
+// x and y are in 1/256th of a lattice cell. The calculations are done with 16
+// bits of fraction.
+func simplexNoise(seed, x, y uint32) int32 {
+	const (
+		one = 65536
+		f2  = 23984 // (√3-1)/2
+		g2  = 13848 // (3-√3)/6
+	)
+	px, py := int64(x)<<8, int64(y)<<8
+	// Skew to find the simplex cell.
+	s := (px + py) * f2 >> 16
+	i, j := (px+s)>>16, (py+s)>>16
+	t := (i + j) * g2
+	x0, y0 := px-(i*one-t), py-(j*one-t)
+	var i1, j1 int64
+	if x0 > y0 {
+		i1 = 1
+	} else {
+		j1 = 1
+	}
+	corners := [3][4]int64{
+		{0, 0, x0, y0},
+		{i1, j1, x0 - i1*one + g2, y0 - j1*one + g2},
+		{1, 1, x0 - one + 2*g2, y0 - one + 2*g2},
+	}
+	var n int64
+	for _, c := range corners {
+		tt := one/2 - (c[2]*c[2]+c[3]*c[3])>>16
+		if tt <= 0 {
+			continue
+		}
+		tt = tt * tt >> 16
+		tt = tt * tt >> 16
+		g := latticeGrad(seed, uint32(i+c[0]), uint32(j+c[1]))
+		n += tt * (g[0]*c[2] + g[1]*c[3]) >> 16
+	}
+	// The sum is scaled by 70 to be within about ±1.
+	return int32(MinMax(int(128+n*70*127>>16), 0, 255))
+}
+
+// sinLUT is a quarter of a sine wave; sinLUT[i] = 32767*sin(i*π/512).
+var sinLUT [257]int16
+
+func setupSin() {
+	for i := range sinLUT {
+		sinLUT[i] = int16(math.Floor(32767*math.Sin(float64(i)*math.Pi/512) + 0.5))
+	}
+}
+
+// sin16 returns the sine of a 16 bits angle, where 65536 is a full turn, in
+// the range [-32767, 32767].
+func sin16(a uint16) int32 {
+	x := int32(a & 0x3fff)
+	if a&0x4000 != 0 {
+		x = 0x4000 - x
+	}
+	i := x >> 6
+	v := int32(sinLUT[i])
+	if f := x & 63; f != 0 {
+		v += (int32(sinLUT[i+1]) - v) * f >> 6
+	}
+	if a&0x8000 != 0 {
+		return -v
+	}
+	return v
+}
+
+// tri16 returns a triangle wave in phase with sin16, in the range [-32767,
+// 32767].
+func tri16(a uint16) int32 {
+	d := int32(a+0x4000) - 32768
+	if d < 0 {
+		d = -d
+	}
+	return MinMax32(32768-2*d, -32767, 32767)
+}
+
+// saw16 returns a rising saw wave, in the range [-32767, 32767].
+func saw16(a uint16) int32 {
+	return MinMax32(int32(a)-32768, -32767, 32767)
+}
+
 // MovePerHour is the number of movement per hour.
 //
 // Can be either positive or negative. Maximum supported value is ±3600000, 1000
@@ -118,43 +411,34 @@ type MovePerHour SValue
 
 // Eval is not a Value implementation but it leverages an inner one.
 func (m *MovePerHour) Eval(timeMS uint32, l int, cycle int) int {
+	return m.eval(timeMS, l, cycle, 1)
+}
+
+// Eval256 is like Eval except that the result is in 1/256th of a move and
+// cycle is still expressed in moves. It is used for sub-pixel rendering.
+func (m *MovePerHour) Eval256(timeMS uint32, l int, cycle int) int {
+	return m.eval(timeMS, l, cycle, 256)
+}
+
+func (m *MovePerHour) eval(timeMS uint32, l int, cycle int, scale int64) int {
 	s := SValue(*m)
 	// Prevent overflows.
-	v := MinMax32(s.Eval(timeMS, l), -3600000, 3600000)
+	v := int64(MinMax32(s.Eval(timeMS, l), -3600000, 3600000)) * scale
 	// TODO(maruel): Reduce the amount of int64 code in there yet keeping it from
 	// overflowing.
 	// offset ranges [0, 3599999]
 	offset := timeMS % 3600000
 	// (1<<32)/3600000 = 1193 is too low. Temporarily upgrade to int64 to
 	// calculate the value.
-	low := int64(offset) * int64(v) / 3600000
+	low := int64(offset) * v / 3600000
 	hour := timeMS / 3600000
-	high := int64(hour) * int64(v)
+	high := int64(hour) * v
 	if cycle != 0 {
-		return int((low + high) % int64(cycle))
+		return int((low + high) % (int64(cycle) * scale))
 	}
 	return int(low + high)
 }
 
-/*
-// Equation evaluate an equation at every call.
-type Equation struct {
-	V string
-	f func(timeMS uint32) int32
-}
-
-// Eval implements Value.
-func (e *Equation) Eval(timeMS uint32) int32 {
-	// Compiles the equation to an actual value and precompile it.
-	if e.f == nil {
-		e.f = func(timeMS uint32) int32 {
-			return 0
-		}
-	}
-	return e.f(timeMS)
-}
-*/
-
 // Scalers
 
 // Bell is a "good enough" approximation of a gaussian curve by using 2
@@ -185,6 +469,14 @@ func (b *Bell) Scale(v uint16) uint16 {
 //
 // They are modeled against CSS transitions.
 // https://www.w3.org/TR/web-animations/#scaling-using-a-cubic-bezier-curve
+//
+// In addition to the constants below, the CSS timing functions
+// "cubic-bezier(x1,y1,x2,y2)", "steps(n,start|end|jump-start|jump-end|jump-none|jump-both)",
+// "linear", "step-start" and "step-end" are supported.
+//
+// Some curves overshoot, that is they go below 0 or above 65535. Scale() and
+// Scale8() clamp the output so they are safe to use as a Mix() gradient, as
+// done by Transition and Loop. Use ScaleSigned() to get the overshoot.
 type Curve string
 
 // All the kind of known curves.
@@ -197,6 +489,10 @@ const (
 	StepStart  Curve = "steps(1,start)"
 	StepMiddle Curve = "steps(1,middle)"
 	StepEnd    Curve = "steps(1,end)"
+	Bounce     Curve = "bounce"  // bounces at the end like a dropped ball.
+	Elastic    Curve = "elastic" // overshoots and oscillates around the end like a rubber band.
+	Back       Curve = "back"    // overshoots the end then comes back.
+	Spring     Curve = "spring"  // damped spring oscillating around the end.
 )
 
 var lutCache map[Curve]fastbezier.LUT
@@ -212,16 +508,58 @@ func setupCache() map[Curve]fastbezier.LUT {
 	return cache
 }
 
+// signedCache contains the physics-style curves, which may overshoot.
+var signedCache map[Curve]signedLUT
+
+func setupSignedCache() map[Curve]signedLUT {
+	return map[Curve]signedLUT{
+		Bounce: makeSignedLUT(func(x float64) float64 {
+			const n1 = 7.5625
+			const d1 = 2.75
+			switch {
+			case x < 1/d1:
+				return n1 * x * x
+			case x < 2/d1:
+				x -= 1.5 / d1
+				return n1*x*x + 0.75
+			case x < 2.5/d1:
+				x -= 2.25 / d1
+				return n1*x*x + 0.9375
+			default:
+				x -= 2.625 / d1
+				return n1*x*x + 0.984375
+			}
+		}),
+		Elastic: makeSignedLUT(func(x float64) float64 {
+			return math.Pow(2, -10*x)*math.Sin((10*x-0.75)*2*math.Pi/3) + 1
+		}),
+		Back: makeSignedLUT(func(x float64) float64 {
+			const c1 = 1.70158
+			return 1 + (c1+1)*math.Pow(x-1, 3) + c1*math.Pow(x-1, 2)
+		}),
+		Spring: makeSignedLUT(func(x float64) float64 {
+			return 1 - math.Exp(-6*x)*math.Cos(5*math.Pi*x)
+		}),
+	}
+}
+
 func init() {
 	lutCache = setupCache()
+	signedCache = setupSignedCache()
+	setupSin()
+	setupKernels()
 }
 
 // Scale scales input [0, 65535] to output [0, 65535] using the curve
 // requested.
+//
+// Overshooting curves are clamped.
 func (c Curve) Scale(intensity uint16) uint16 {
 	switch c {
 	case Ease, EaseIn, EaseInOut, EaseOut, "":
 		return lutCache[c].Eval(intensity)
+	case Bounce, Elastic, Back, Spring:
+		return clamp16(signedCache[c].Eval(intensity))
 	case Direct:
 		return intensity
 	case StepStart:
@@ -240,15 +578,221 @@ func (c Curve) Scale(intensity uint16) uint16 {
 		}
 		return 0
 	default:
+		if d := c.def(); d != nil {
+			return clamp16(d.scale(intensity))
+		}
 		return lutCache[""].Eval(intensity)
 	}
 }
 
-// Scale8 saves on casting.
+// ScaleSigned is like Scale but doesn't clamp the output for curves that
+// overshoot, e.g. Back may return a value above 65535.
+func (c Curve) ScaleSigned(intensity uint16) int32 {
+	switch c {
+	case Bounce, Elastic, Back, Spring:
+		return signedCache[c].Eval(intensity)
+	case Ease, EaseIn, EaseInOut, EaseOut, "", Direct, StepStart, StepMiddle, StepEnd:
+		return int32(c.Scale(intensity))
+	default:
+		if d := c.def(); d != nil {
+			return d.scale(intensity)
+		}
+		return int32(c.Scale(intensity))
+	}
+}
+
+// Scale8 saves on casting. Overshooting curves are clamped.
 func (c Curve) Scale8(intensity uint16) uint8 {
 	return uint8(c.Scale(intensity) >> 8)
 }
 
+// curveDef is a parsed Curve that is not one of the predefined constants.
+type curveDef struct {
+	lut    fastbezier.LUT // Set for cubic-bezier()
+	signed signedLUT      // Set for cubic-bezier() that overshoots
+	steps  uint32         // Set for steps()
+	jump   string         // One of "start", "end", "none" or "both"
+}
+
+// curveDefs caches the parsed curves, keyed by Curve. It is a sync.Map since
+// it's read on every Scale() call and written once per curve.
+var curveDefs sync.Map
+
+// def returns the parsed curve, or nil if the curve is invalid.
+func (c Curve) def() *curveDef {
+	if d, ok := curveDefs.Load(c); ok {
+		return d.(*curveDef)
+	}
+	d, err := parseCurve(string(c))
+	if err != nil {
+		// Cache the failure too, so it's not parsed on every call.
+		d = nil
+	}
+	curveDefs.Store(c, d)
+	return d
+}
+
+func (d *curveDef) scale(intensity uint16) int32 {
+	if d.lut != nil {
+		return int32(d.lut.Eval(intensity))
+	}
+	if d.signed != nil {
+		return d.signed.Eval(intensity)
+	}
+	// The steps can be up to 65535 so the multiplications are done in int64.
+	n := int64(d.steps)
+	step := int64(uint32(intensity) * d.steps / 65535)
+	switch d.jump {
+	case "start":
+		return int32(min64(step+1, n) * 65535 / n)
+	case "none":
+		return int32(min64(step, n-1) * 65535 / (n - 1))
+	case "both":
+		return int32(min64(step+1, n+1) * 65535 / (n + 1))
+	default:
+		return int32(step * 65535 / n)
+	}
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// parseCurve parses a CSS timing function.
+func parseCurve(s string) (*curveDef, error) {
+	switch s {
+	case "linear":
+		return &curveDef{lut: fastbezier.Make(0, 0, 1, 1, 18)}, nil
+	case "step-start":
+		return &curveDef{steps: 1, jump: "start"}, nil
+	case "step-end":
+		return &curveDef{steps: 1, jump: "end"}, nil
+	}
+	i := strings.IndexByte(s, '(')
+	if i == -1 || !strings.HasSuffix(s, ")") {
+		return nil, fmt.Errorf("curve: unknown curve %q", s)
+	}
+	args := strings.Split(s[i+1:len(s)-1], ",")
+	for j := range args {
+		args[j] = strings.TrimSpace(args[j])
+	}
+	switch s[:i] {
+	case "cubic-bezier":
+		if len(args) != 4 {
+			return nil, fmt.Errorf("curve: %q requires 4 arguments", s)
+		}
+		var p [4]float32
+		for j, a := range args {
+			f, err := strconv.ParseFloat(a, 32)
+			if err != nil {
+				return nil, fmt.Errorf("curve: %w", err)
+			}
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				return nil, fmt.Errorf("curve: %q values must be finite", s)
+			}
+			p[j] = float32(f)
+		}
+		if p[0] < 0 || p[0] > 1 || p[2] < 0 || p[2] > 1 {
+			return nil, fmt.Errorf("curve: %q x values must be in [0, 1]", s)
+		}
+		if p[1] < 0 || p[1] > 1 || p[3] < 0 || p[3] > 1 {
+			// fastbezier doesn't support overshooting.
+			x1, y1, x2, y2 := float64(p[0]), float64(p[1]), float64(p[2]), float64(p[3])
+			return &curveDef{signed: makeSignedLUT(func(x float64) float64 {
+				return cubicBezier(x1, y1, x2, y2, x)
+			})}, nil
+		}
+		return &curveDef{lut: fastbezier.Make(p[0], p[1], p[2], p[3], 18)}, nil
+	case "steps":
+		if len(args) != 1 && len(args) != 2 {
+			return nil, fmt.Errorf("curve: %q requires 1 or 2 arguments", s)
+		}
+		n, err := strconv.ParseUint(args[0], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("curve: %w", err)
+		}
+		d := &curveDef{steps: uint32(n), jump: "end"}
+		if len(args) == 2 {
+			switch args[1] {
+			case "start", "jump-start":
+				d.jump = "start"
+			case "end", "jump-end":
+			case "jump-none":
+				d.jump = "none"
+			case "jump-both":
+				d.jump = "both"
+			default:
+				return nil, fmt.Errorf("curve: %q has unknown step position", s)
+			}
+		}
+		if n < 1 || (d.jump == "none" && n < 2) {
+			return nil, fmt.Errorf("curve: %q has an invalid number of steps", s)
+		}
+		return d, nil
+	default:
+		return nil, fmt.Errorf("curve: unknown curve %q", s)
+	}
+}
+
+// signedLUT is similar to fastbezier.LUT except that the values may be outside
+// [0, 65535] for curves that overshoot.
+type signedLUT []int32
+
+// makeSignedLUT precalculates f over [0, 1] in 256 steps.
+//
+// f(0) must be 0 and f(1) must be 1; the extremities are forced to these
+// values.
+func makeSignedLUT(f func(x float64) float64) signedLUT {
+	const steps = 256
+	l := make(signedLUT, steps+1)
+	for i := 1; i < steps; i++ {
+		l[i] = int32(math.Floor(65535*f(float64(i)/steps) + 0.5))
+	}
+	l[steps] = 65535
+	return l
+}
+
+// Eval returns the linearly interpolated value at x.
+func (l signedLUT) Eval(x uint16) int32 {
+	steps := uint32(len(l) - 1)
+	x32 := uint32(x) * steps
+	i := x32 / 65535
+	if i >= steps {
+		return l[steps]
+	}
+	f := int64(x32 - i*65535)
+	return l[i] + int32(int64(l[i+1]-l[i])*f/65535)
+}
+
+// clamp16 clamps an overshooting value to [0, 65535].
+func clamp16(v int32) uint16 {
+	return uint16(MinMax32(v, 0, 65535))
+}
+
+// cubicBezier returns y for x on the curve (0,0), (x1,y1), (x2,y2), (1,1).
+//
+// x1 and x2 must be in [0, 1] so x is monotonic.
+func cubicBezier(x1, y1, x2, y2, x float64) float64 {
+	bezier := func(p1, p2, t float64) float64 {
+		u := 1 - t
+		return 3*u*u*t*p1 + 3*u*t*t*p2 + t*t*t
+	}
+	// Bisection is slow but it's only done once per curve.
+	lo, hi := 0., 1.
+	for i := 0; i < 32; i++ {
+		t := (lo + hi) / 2
+		if bezier(x1, x2, t) < x {
+			lo = t
+		} else {
+			hi = t
+		}
+	}
+	return bezier(y1, y2, (lo+hi)/2)
+}
+
 // Interpolation specifies a way to scales a pixel strip.
 type Interpolation string
 
@@ -257,6 +801,9 @@ const (
 	NearestSkip Interpolation = "nearestskip" // Selects the nearest pixel but when upscaling, skips on missing pixels.
 	Nearest     Interpolation = "nearest"     // Selects the nearest pixel, gives a blocky view.
 	Linear      Interpolation = "linear"      // Linear interpolation, recommended and default value.
+	Cubic       Interpolation = "cubic"       // Catmull-Rom cubic interpolation, sharper than linear.
+	Lanczos     Interpolation = "lanczos"     // Lanczos interpolation with a 2 pixels window, sharpest but may ring.
+	Box         Interpolation = "box"         // Averages the covered pixels when downscaling, linear when upscaling.
 )
 
 // Scale interpolates a frame into another using integers as much as possible
@@ -268,18 +815,47 @@ func (i Interpolation) Scale(in, out Frame) {
 		return
 	}
 	switch i {
+	case Box:
+		if li > lo {
+			for i := range out {
+				start := i * li / lo
+				end := (i + 1) * li / lo
+				var r, g, b int
+				for _, c := range in[start:end] {
+					r += int(c.R)
+					g += int(c.G)
+					b += int(c.B)
+				}
+				n := end - start
+				out[i] = Color{uint8((r + n/2) / n), uint8((g + n/2) / n), uint8((b + n/2) / n)}
+			}
+			return
+		}
+		fallthrough
 	case Linear:
 		for i := range out {
-			x := (i*li + li/2) / lo
+			x, frac := srcPos(i, li, lo)
 			c := in[x]
-			if x < li-1 {
-				gradient := uint8(127)
-				c.Mix(in[x+1], gradient)
+			if frac != 0 {
+				c.Mix(in[x+1], frac)
 			}
 			out[i] = c
-			//a := in[(i*li+li/2)/lo]
-			//b := in[(i*li+li/2)/lo]
-			//out[i] = (a + b) / 2
+		}
+	case Cubic, Lanczos:
+		w := &cubicWeights
+		if i == Lanczos {
+			w = &lanczosWeights
+		}
+		for i := range out {
+			x, frac := srcPos(i, li, lo)
+			var r, g, b int32
+			for k, f := range w[frac] {
+				c := in[MinMax(x-1+k, 0, li-1)]
+				r += int32(c.R) * int32(f)
+				g += int32(c.G) * int32(f)
+				b += int32(c.B) * int32(f)
+			}
+			out[i] = Color{kernel8(r), kernel8(g), kernel8(b)}
 		}
 	case NearestSkip:
 		if li < lo {
@@ -301,6 +877,318 @@ func (i Interpolation) Scale(in, out Frame) {
 	}
 }
 
+// srcPos returns the source pixel and the fractional position toward the next
+// source pixel in 1/256th for destination pixel i, matching the pixel centers.
+func srcPos(i, li, lo int) (int, uint8) {
+	pos := MinMax((2*i+1)*li*128/lo-128, 0, (li-1)*256)
+	return pos >> 8, uint8(pos)
+}
+
+// kernelOne is 1.0 in the fixed point kernel weights.
+const kernelOne = 4096
+
+// cubicWeights and lanczosWeights are the 4 taps weights for each 1/256th of
+// pixel, for the source pixels at offsets -1, 0, 1, 2.
+var cubicWeights, lanczosWeights [256][4]int16
+
+func setupKernels() {
+	sinc := func(x float64) float64 {
+		if x == 0 {
+			return 1
+		}
+		return math.Sin(math.Pi*x) / (math.Pi * x)
+	}
+	for i := range cubicWeights {
+		t := float64(i) / 256
+		t2 := t * t
+		t3 := t2 * t
+		setKernel(&cubicWeights[i], [4]float64{
+			(-t3 + 2*t2 - t) / 2,
+			(3*t3 - 5*t2 + 2) / 2,
+			(-3*t3 + 4*t2 + t) / 2,
+			(t3 - t2) / 2,
+		})
+		var l [4]float64
+		for k := range l {
+			x := t + 1 - float64(k)
+			l[k] = sinc(x) * sinc(x/2)
+		}
+		setKernel(&lanczosWeights[i], l)
+	}
+}
+
+// setKernel normalizes the weights so their sum is exactly kernelOne.
+func setKernel(dst *[4]int16, w [4]float64) {
+	sum := w[0] + w[1] + w[2] + w[3]
+	total := 0
+	for k := range w {
+		dst[k] = int16(math.Floor(w[k]/sum*kernelOne + 0.5))
+		total += int(dst[k])
+	}
+	dst[1] += int16(kernelOne - total)
+}
+
+// kernel8 converts back a weighted sum to a channel value.
+func kernel8(v int32) uint8 {
+	return uint8(MinMax32((v+kernelOne/2)/kernelOne, 0, 255))
+}
+
+// Effect specifies how a frame is replaced by another one during a transition.
+type Effect string
+
+// All the kinds of transition effects.
+const (
+	Crossfade Effect = "crossfade"  // Blends both frames, recommended and default value.
+	WipeLeft  Effect = "wipe-left"  // The new frame is uncovered from the right to the left.
+	WipeRight Effect = "wipe-right" // The new frame is uncovered from the left to the right.
+	CenterOut Effect = "center-out" // The new frame is uncovered from the center to both ends.
+	PushLeft  Effect = "push-left"  // The new frame enters from the right and pushes the old one out.
+	PushRight Effect = "push-right" // The new frame enters from the left and pushes the old one out.
+	Dissolve  Effect = "dissolve"   // Each pixel switches at its own deterministic pseudo-random time.
+	FadeBlack Effect = "fade-black" // The old frame fades to black then the new frame fades in.
+)
+
+var knownEffects = []Effect{"", Crossfade, WipeLeft, WipeRight, CenterOut, PushLeft, PushRight, Dissolve, FadeBlack}
+
+// Mix replaces pixels with next as progress goes from 0 to 65535.
+//
+// progress 0 leaves pixels untouched, 65535 means pure next. The progress is
+// expected to have been scaled by a Curve already.
+func (e Effect) Mix(pixels, next Frame, progress uint16) {
+	l := len(pixels)
+	if l == 0 {
+		return
+	}
+	if progress == 65535 {
+		copy(pixels, next)
+		return
+	}
+	switch e {
+	case WipeLeft, WipeRight:
+		// Position of the edge in 1/256th of pixel, to antialias the edge.
+		edge := int(progress) * l >> 8
+		for i := range pixels {
+			j := i
+			if e == WipeLeft {
+				j = l - 1 - i
+			}
+			mixCover(&pixels[j], next[j], edge-i*256)
+		}
+	case CenterOut:
+		radius := int(progress) * l >> 9
+		for i := range pixels {
+			// Distance between the pixel center and the strip center in 1/256th of
+			// pixel.
+			d := (2*i + 1 - l) * 128
+			if d < 0 {
+				d = -d
+			}
+			mixCover(&pixels[i], next[i], radius-d+128)
+		}
+	case PushLeft:
+		k := (int(progress)*l + 32767) / 65535
+		copy(pixels, pixels[k:])
+		copy(pixels[l-k:], next)
+	case PushRight:
+		k := (int(progress)*l + 32767) / 65535
+		copy(pixels[k:], pixels)
+		copy(pixels, next[l-k:])
+	case Dissolve:
+		for i := range pixels {
+			// Each pixel fades over 1/8th of the transition, starting at a random
+			// offset.
+			start := int(hash32(uint32(i))>>16) * 7 / 8
+			mixCover(&pixels[i], next[i], (int(progress)-start)*8>>8)
+		}
+	case FadeBlack:
+		if progress < 32768 {
+			pixels.Dim(255 - uint8(progress>>7))
+			return
+		}
+		copy(pixels, next)
+		pixels.Dim(uint8((progress - 32768) >> 7))
+	default:
+		pixels.Mix(next, uint8(progress>>8))
+	}
+}
+
+// mixCover blends d into c in proportion to cover, which is clamped to
+// [0, 256].
+func mixCover(c *Color, d Color, cover int) {
+	if cover >= 256 {
+		*c = d
+	} else if cover > 0 {
+		c.Mix(d, uint8(cover))
+	}
+}
+
+// Blend specifies how a layer is combined with the pixels below it.
+//
+// Colors are considered premultiplied by their coverage, which is the value
+// of their brightest channel. This means that black is fully transparent with
+// BlendNormal, so a sparse pattern can be overlaid on a background without
+// brightening it. Use MixAlpha to specify the coverage explicitly.
+type Blend string
+
+// All the kinds of blend modes.
+const (
+	BlendNormal     Blend = "normal"     // Porter-Duff 'over'; recommended and default value.
+	BlendMultiply   Blend = "multiply"   // Darkens; black stays black, white is transparent.
+	BlendScreen     Blend = "screen"     // Brightens; white stays white, black is transparent.
+	BlendLighten    Blend = "lighten"    // Keeps the maximum of each channel.
+	BlendDarken     Blend = "darken"     // Keeps the minimum of each channel.
+	BlendDifference Blend = "difference" // Absolute difference of each channel.
+	BlendAdd        Blend = "add"        // Adds with saturation, like Frame.Add.
+)
+
+var knownBlends = []Blend{"", BlendNormal, BlendMultiply, BlendScreen, BlendLighten, BlendDarken, BlendDifference, BlendAdd}
+
+// Mix blends layer on top of pixels.
+//
+// opacity 0 leaves pixels untouched, 255 means the layer is fully applied.
+func (b Blend) Mix(pixels, layer Frame, opacity uint8) {
+	b.MixAlpha(pixels, layer, nil, opacity)
+}
+
+// MixAlpha is like Mix but uses the luminance of alpha as the coverage of
+// each pixel of layer instead of its brightest channel.
+//
+// The colors of layer are then not premultiplied, so dark colors can be drawn
+// opaquely. When alpha is nil, it is the same as Mix.
+func (b Blend) MixAlpha(pixels, layer, alpha Frame, opacity uint8) {
+	if opacity == 0 {
+		return
+	}
+	for i := range pixels {
+		d := &pixels[i]
+		s := layer[i]
+		cover := opacity
+		if alpha != nil {
+			if cover = mul255(alpha[i].Luminance(), opacity); cover == 0 {
+				continue
+			}
+		}
+		var r Color
+		switch b {
+		case BlendAdd:
+			r = *d
+			r.Add(s)
+		case BlendMultiply, BlendScreen, BlendLighten, BlendDarken, BlendDifference:
+			r = Color{b.channel(d.R, s.R), b.channel(d.G, s.G), b.channel(d.B, s.B)}
+		default:
+			if alpha != nil {
+				r = s
+				break
+			}
+			t := 255 - maxChannel(s)
+			r = Color{s.R + mul255(d.R, t), s.G + mul255(d.G, t), s.B + mul255(d.B, t)}
+		}
+		if cover == 255 {
+			*d = r
+		} else {
+			d.Mix(r, cover)
+		}
+	}
+}
+
+// channel blends one channel of the layer s on top of d.
+func (b Blend) channel(d, s uint8) uint8 {
+	switch b {
+	case BlendMultiply:
+		return mul255(d, s)
+	case BlendScreen:
+		return 255 - mul255(255-d, 255-s)
+	case BlendLighten:
+		if s > d {
+			return s
+		}
+		return d
+	case BlendDarken:
+		if s < d {
+			return s
+		}
+		return d
+	default:
+		if s > d {
+			return s - d
+		}
+		return d - s
+	}
+}
+
+// mul255 returns a*b/255 rounded.
+func mul255(a, b uint8) uint8 {
+	v := uint16(a)*uint16(b) + 128
+	return uint8((v + v>>8) >> 8)
+}
+
+// maxChannel returns the value of the brightest channel.
+func maxChannel(c Color) uint8 {
+	m := c.R
+	if c.G > m {
+		m = c.G
+	}
+	if c.B > m {
+		m = c.B
+	}
+	return m
+}
+
+// Channel selects which component of a color is used as a level.
+type Channel string
+
+// All the kinds of channels.
+const (
+	ChannelLuminance Channel = "luminance" // Perceived brightness, recommended and default value.
+	ChannelRed       Channel = "red"
+	ChannelGreen     Channel = "green"
+	ChannelBlue      Channel = "blue"
+	ChannelMax       Channel = "max" // Brightest of the three channels.
+)
+
+var knownChannels = []Channel{"", ChannelLuminance, ChannelRed, ChannelGreen, ChannelBlue, ChannelMax}
+
+// Eval returns the level of the color c for this channel.
+func (ch Channel) Eval(c Color) uint8 {
+	switch ch {
+	case ChannelRed:
+		return c.R
+	case ChannelGreen:
+		return c.G
+	case ChannelBlue:
+		return c.B
+	case ChannelMax:
+		return maxChannel(c)
+	default:
+		return c.Luminance()
+	}
+}
+
+// NoiseKind specifies the algorithm used to generate smooth noise.
+type NoiseKind string
+
+// All the kinds of noise.
+const (
+	NoiseValue   NoiseKind = "value"   // Interpolated random values; blocky but cheapest, and default value.
+	NoisePerlin  NoiseKind = "perlin"  // Interpolated random gradients.
+	NoiseSimplex NoiseKind = "simplex" // Gradients on a triangular grid; less directional artifacts.
+)
+
+var knownNoiseKinds = []NoiseKind{"", NoiseValue, NoisePerlin, NoiseSimplex}
+
+// Eval returns the noise in [0, 255] at x and y, which are in 1/256th of a
+// noise feature.
+func (k NoiseKind) Eval(seed, x, y uint32) int32 {
+	switch k {
+	case NoisePerlin:
+		return perlinNoise(seed, x, y)
+	case NoiseSimplex:
+		return simplexNoise(seed, x, y)
+	default:
+		return valueNoise(seed, x, y)
+	}
+}
+
 //
 
 //const epsilon = 1e-7
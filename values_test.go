@@ -106,10 +106,81 @@ func TestRand(t *testing.T) {
 	}
 }
 
+func TestOscillator(t *testing.T) {
+	data := []struct {
+		w        Wave
+		timeMS   uint32
+		expected int32
+	}{
+		{Sine, 0, 0},
+		{Sine, 250, 127},
+		{Sine, 500, 255},
+		{Sine, 750, 127},
+		{Sine, 1000, 0},
+		{Triangle, 0, 0},
+		{Triangle, 125, 64},
+		{Triangle, 500, 255},
+		{Sawtooth, 0, 0},
+		{Sawtooth, 500, 128},
+		{Sawtooth, 999, 255},
+		{Square, 0, 0},
+		{Square, 499, 0},
+		{Square, 500, 255},
+	}
+	for i, line := range data {
+		o := Oscillator{Wave: line.w, PeriodMS: 1000, Max: 255}
+		if v := o.Eval(line.timeMS, 0); v != line.expected {
+			t.Fatalf("%d: %s.Eval(%d) = %d, expected %d", i, line.w, line.timeMS, v, line.expected)
+		}
+	}
+	o := Oscillator{PeriodMS: 1000, PhaseMS: 500, Min: 10, Max: -10}
+	if v := o.Eval(0, 0); v != -10 {
+		t.Fatal(v)
+	}
+	if v := (&Oscillator{Min: 3}).Eval(10, 0); v != 3 {
+		t.Fatal(v)
+	}
+}
+
+func TestKeyframes(t *testing.T) {
+	frames := []Keyframe{{100, 10, Direct}, {200, 20, StepEnd}, {300, -20, Direct}, {400, 0, Direct}}
+	data := []struct {
+		mode     KeyframesMode
+		timeMS   uint32
+		expected int32
+	}{
+		{KeyframesHold, 0, 10},
+		{KeyframesHold, 100, 10},
+		{KeyframesHold, 150, 15},
+		{KeyframesHold, 200, 20},
+		{KeyframesHold, 250, 20},
+		{KeyframesHold, 299, 20},
+		{KeyframesHold, 300, -20},
+		{KeyframesHold, 350, -10},
+		{KeyframesHold, 400, 0},
+		{KeyframesHold, 1000, 0},
+		{KeyframesLoop, 400, 10},
+		{KeyframesLoop, 450, 15},
+		{KeyframesLoop, 1000, 10},
+		{KeyframesPingPong, 450, -10},
+		{KeyframesPingPong, 700, 10},
+		{KeyframesPingPong, 750, 15},
+	}
+	for i, line := range data {
+		k := Keyframes{Frames: frames, Mode: line.mode}
+		if v := k.Eval(line.timeMS, 0); v != line.expected {
+			t.Fatalf("%d: %s.Eval(%d) = %d, expected %d", i, line.mode, line.timeMS, v, line.expected)
+		}
+	}
+	if v := (&Keyframes{}).Eval(10, 0); v != 0 {
+		t.Fatal(v)
+	}
+}
+
 // Scalers
 
 func TestCurve_limits(t *testing.T) {
-	for _, v := range []Curve{Curve(""), Ease, EaseIn, EaseInOut, EaseOut, Direct} {
+	for _, v := range []Curve{Curve(""), Ease, EaseIn, EaseInOut, EaseOut, Direct, "linear", "cubic-bezier(0.1,0.7,1,0.1)", "steps(4)", "steps(3,jump-none)", "step-end", Bounce, Elastic, Back, Spring, "cubic-bezier(0.5,-1,0.5,2)"} {
 		if s := v.Scale(0); s != 0 {
 			t.Fatalf("limit low %d != 0", s)
 		}
@@ -132,6 +203,28 @@ func TestCurve_values(t *testing.T) {
 		{EaseOut, half, 0xaf1d},
 		{Curve(""), half, 0xaf1d},
 		{Direct, half, half},
+		{"cubic-bezier(0.42, 0, 0.58, 1)", half, 0x7ffe},
+		{"cubic-bezier(0.5,-1,0.5,2)", 1000, 0},
+		{"linear", 1000, 1000},
+		{"steps(4)", 16383, 0},
+		{"steps(4)", 16384, 16383},
+		{"steps(4,end)", 65534, 49151},
+		{"steps(4,start)", 0, 16383},
+		{"steps(4,jump-start)", 16384, 32767},
+		{"steps(3,jump-none)", 0, 0},
+		{"steps(3,jump-none)", half, half},
+		{"steps(3,jump-none)", 65534, 65535},
+		{"steps(3,jump-both)", 0, 16383},
+		{"steps(3,jump-both)", 65535, 65535},
+		{"steps(40000)", 65000, 64999},
+		{"steps(40000)", 65535, 65535},
+		{"steps(65535,jump-both)", 65535, 65535},
+		{"step-start", 0, 65535},
+		{"step-end", 65534, 0},
+		// Invalid curves fall back to EaseOut.
+		{"steps(0)", half, 0xaf1d},
+		{"cubic-bezier(2,0,0,1)", half, 0xaf1d},
+		{"bouncy", half, 0xaf1d},
 	}
 	for i, line := range data {
 		if v := line.t.Scale(line.i); v != line.expected {
@@ -140,9 +233,34 @@ func TestCurve_values(t *testing.T) {
 	}
 }
 
+func TestCurve_overshoot(t *testing.T) {
+	data := []struct {
+		t        Curve
+		i        uint16
+		expected int32
+	}{
+		{Back, 0, 0},
+		{Back, 52428, 68580},
+		{Back, 65535, 65535},
+		{Elastic, 6554, 81895},
+		{Spring, 16384, 75875},
+		{Bounce, 32768, 50175},
+		{"cubic-bezier(0.5,-1,0.5,2)", 6554, -10231},
+		{Ease, 32767, 0xcd01},
+	}
+	for i, line := range data {
+		if v := line.t.ScaleSigned(line.i); v != line.expected {
+			t.Fatalf("%d: %v.ScaleSigned(%v) = %v, expected %v", i, line.t, line.i, v, line.expected)
+		}
+		if v := line.t.Scale(line.i); int32(v) != MinMax32(line.expected, 0, 65535) {
+			t.Fatalf("%d: %v.Scale(%v) = %v, expected clamped %v", i, line.t, line.i, v, line.expected)
+		}
+	}
+}
+
 func TestInterpolationEmpty(t *testing.T) {
 	b := make(Frame, 1)
-	for _, v := range []Interpolation{Interpolation(""), NearestSkip, Nearest, Linear} {
+	for _, v := range []Interpolation{Interpolation(""), NearestSkip, Nearest, Linear, Cubic, Lanczos, Box} {
 		v.Scale(nil, nil)
 		v.Scale(nil, b)
 		v.Scale(b, nil)
@@ -180,15 +298,24 @@ func TestInterpolation(t *testing.T) {
 		{Nearest, input, Frame{yellow}},
 		{Nearest, input, Frame{green, magenta}},
 		{Nearest, input, Frame{green, yellow, magenta}},
-		// TODO(maruel): This is broken.
-		/*{
+		{
 			Linear,
 			input,
-			Frame{red, red, green, green, blue, blue, yellow, yellow, cyan, cyan, magenta, magenta, white, white},
-		},*/
-		{Linear, input, Frame{Color{0x80, 0xFF, 0x7F}}},
-		{Linear, input, Frame{Color{0x00, 0x80, 0x7F}, Color{0xFF, 0x7F, 0xFF}}},
-		{Linear, input, Frame{Color{0x0, 0x80, 0x7F}, Color{0x80, 0xFF, 0x7F}, Color{0xFF, 0x7F, 0xFF}}},
+			Frame{
+				red, {0xBF, 0x40, 0x00}, {0x3F, 0xC0, 0x00}, {0x00, 0xBF, 0x40}, {0x00, 0x3F, 0xC0}, {0x40, 0x40, 0xBF},
+				{0xC0, 0xC0, 0x3F}, {0xBF, 0xFF, 0x40}, {0x3F, 0xFF, 0xC0}, {0x40, 0xBF, 0xFF}, {0xC0, 0x3F, 0xFF},
+				{0xFF, 0x40, 0xFF}, {0xFF, 0xC0, 0xFF}, white,
+			},
+		},
+		{Linear, input, Frame{yellow}},
+		{Linear, input, Frame{Color{0x00, 0xBF, 0x40}, Color{0xC0, 0x3F, 0xFF}}},
+		{Linear, input, Frame{Color{0x55, 0xAA, 0x00}, yellow, Color{0xFF, 0x55, 0xFF}}},
+		{Box, input, Frame{Color{0x55, 0x55, 0x55}, Color{0xBF, 0xBF, 0xBF}}},
+		{Box, Frame{red, green}, Frame{red, {0x7F, 0x80, 0x00}, green}},
+		{Cubic, Frame{black, white}, Frame{black, black, {0x4B, 0x4B, 0x4B}, {0xB3, 0xB3, 0xB3}, white, white}},
+		{Lanczos, Frame{black, white}, Frame{black, black, {0x4E, 0x4E, 0x4E}, {0xB0, 0xB0, 0xB0}, white, white}},
+		{Cubic, input, Frame{yellow}},
+		{Lanczos, input, Frame{yellow}},
 	}
 	for i, line := range data {
 		line := line
@@ -202,6 +329,145 @@ func TestInterpolation(t *testing.T) {
 	}
 }
 
+func TestEffect(t *testing.T) {
+	a := Color{0x10, 0x10, 0x10}
+	b := Color{0x20, 0x20, 0x20}
+	c := Color{0x30, 0x30, 0x30}
+	d := Color{0x40, 0x40, 0x40}
+	x := Color{0xFF, 0xFF, 0xFF}
+	y := Color{0xFE, 0xFE, 0xFE}
+	data := []struct {
+		e        Effect
+		progress uint16
+		want     Frame
+	}{
+		{Crossfade, 0, Frame{a, b, c, d}},
+		{Crossfade, 65535, Frame{x, x, y, y}},
+		{WipeRight, 32768, Frame{x, x, c, d}},
+		{WipeRight, 24576, Frame{x, {0x90, 0x90, 0x90}, c, d}},
+		{WipeLeft, 32768, Frame{a, b, y, y}},
+		{CenterOut, 32768, Frame{a, x, y, d}},
+		{PushLeft, 16384, Frame{b, c, d, x}},
+		{PushRight, 32768, Frame{y, y, a, b}},
+		{FadeBlack, 32767, Frame{{}, {}, {}, {}}},
+		{FadeBlack, 32768, Frame{{}, {}, {}, {}}},
+		{Dissolve, 0, Frame{a, b, c, d}},
+		{Dissolve, 65535, Frame{x, x, y, y}},
+	}
+	for i, line := range data {
+		got := Frame{a, b, c, d}
+		line.e.Mix(got, Frame{x, x, y, y}, line.progress)
+		if !got.isEqual(line.want) {
+			t.Fatalf("%d: %s.Mix(%d) = %s; want %s", i, line.e, line.progress, got, line.want)
+		}
+	}
+}
+
+func TestBlend(t *testing.T) {
+	data := []struct {
+		b       Blend
+		s       Color
+		opacity uint8
+		want    Color
+	}{
+		{"", Color{}, 255, Color{0x80, 0x40, 0x00}},
+		{BlendNormal, Color{0x80, 0x00, 0x00}, 255, Color{0xC0, 0x20, 0x00}},
+		{BlendNormal, Color{0xFF, 0x00, 0x00}, 255, Color{0xFF, 0x00, 0x00}},
+		{BlendNormal, Color{0xFF, 0x00, 0x00}, 128, Color{0xBF, 0x20, 0x00}},
+		{BlendNormal, Color{0xFF, 0x00, 0x00}, 0, Color{0x80, 0x40, 0x00}},
+		{BlendMultiply, Color{0xFF, 0x80, 0xFF}, 255, Color{0x80, 0x20, 0x00}},
+		{BlendScreen, Color{0x80, 0x80, 0x80}, 255, Color{0xC0, 0xA0, 0x80}},
+		{BlendLighten, Color{0x40, 0x80, 0x20}, 255, Color{0x80, 0x80, 0x20}},
+		{BlendDarken, Color{0x40, 0x80, 0x20}, 255, Color{0x40, 0x40, 0x00}},
+		{BlendDifference, Color{0x40, 0x80, 0x20}, 255, Color{0x40, 0x40, 0x20}},
+		{BlendAdd, Color{0x90, 0x10, 0x10}, 255, Color{0xFF, 0x50, 0x10}},
+	}
+	for i, line := range data {
+		got := Frame{{0x80, 0x40, 0x00}}
+		line.b.Mix(got, Frame{line.s}, line.opacity)
+		if got[0] != line.want {
+			t.Fatalf("%d: %q.Mix(%s, %d) = %s; want %s", i, line.b, &line.s, line.opacity, &got[0], &line.want)
+		}
+	}
+}
+
+func TestMul255(t *testing.T) {
+	for a := 0; a < 256; a++ {
+		for b := 0; b < 256; b++ {
+			if got, want := mul255(uint8(a), uint8(b)), uint8((a*b*2+255)/510); got != want {
+				t.Fatalf("mul255(%d, %d) = %d; want %d", a, b, got, want)
+			}
+		}
+	}
+}
+
+func TestValueNoise(t *testing.T) {
+	// Lattice points are exact.
+	if v := valueNoise(0, 0, 0); v != latticeValue(0, 0, 0) {
+		t.Fatal(v)
+	}
+	if v := valueNoise(0, 256, 0); v != latticeValue(0, 1, 0) {
+		t.Fatal(v)
+	}
+	if v := valueNoise(0, 128, 0); v != (latticeValue(0, 0, 0)+latticeValue(0, 1, 0))/2 {
+		t.Fatal(v)
+	}
+	// Continuous and in range.
+	prev := valueNoise(1, 0, 300)
+	for x := uint32(1); x < 4096; x++ {
+		v := valueNoise(1, x, 300)
+		if v < 0 || v > 255 {
+			t.Fatalf("%d: %d", x, v)
+		}
+		if d := v - prev; d < -3 || d > 3 {
+			t.Fatalf("%d: %d -> %d", x, prev, v)
+		}
+		prev = v
+	}
+}
+
+func TestNoiseKind(t *testing.T) {
+	for _, k := range knownNoiseKinds {
+		var hist [4]int
+		prev := k.Eval(3, 0, 1000)
+		for x := uint32(1); x < 65536; x++ {
+			v := k.Eval(3, x, 1000)
+			if v < 0 || v > 255 {
+				t.Fatalf("%q: %d: %d", k, x, v)
+			}
+			if d := v - prev; d < -4 || d > 4 {
+				t.Fatalf("%q: %d: %d -> %d", k, x, prev, v)
+			}
+			prev = v
+			hist[v>>6]++
+		}
+		for i, h := range hist {
+			if h == 0 {
+				t.Fatalf("%q: no value in quarter %d", k, i)
+			}
+		}
+	}
+}
+
+func TestSin16(t *testing.T) {
+	data := []struct {
+		a        uint16
+		expected int32
+	}{
+		{0, 0},
+		{0x2000, 23170},
+		{0x4000, 32767},
+		{0x6000, 23170},
+		{0x8000, 0},
+		{0xC000, -32767},
+	}
+	for i, line := range data {
+		if v := sin16(line.a); v != line.expected {
+			t.Fatalf("%d: sin16(%d) = %d, expected %d", i, line.a, v, line.expected)
+		}
+	}
+}
+
 func TestMovePerHour(t *testing.T) {
 	data := []struct {
 		mps      int32
@@ -240,6 +506,20 @@ func TestMovePerHour(t *testing.T) {
 	}
 }
 
+func TestMovePerHour_Eval256(t *testing.T) {
+	m := MovePerHour{Const(3600)}
+	if v := m.Eval256(500, 0, 10); v != 128 {
+		t.Fatal(v)
+	}
+	if v := m.Eval256(10500, 0, 10); v != 128 {
+		t.Fatal(v)
+	}
+	m = MovePerHour{Const(-3600)}
+	if v := m.Eval256(500, 0, 10); v != -128 {
+		t.Fatal(v)
+	}
+}
+
 func BenchmarkSetupCache(b *testing.B) {
 	// Calculate how much this one-time initialization cost is.
 	for i := 0; i < b.N; i++ {